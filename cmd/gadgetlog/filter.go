@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/marcusva/gadget/log"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// timeLayouts are the layouts accepted for the -since and -until options.
+var timeLayouts = []string{
+	time.RFC3339,
+	log.TimeLayout,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// filter decides, which records are shown.
+type filter struct {
+	level  log.Level
+	since  time.Time
+	until  time.Time
+	caller string
+	match  *regexp.Regexp
+}
+
+// parseTime parses an absolute point in time or a duration relative to now,
+// such as "90m" for the last 90 minutes.
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		if d > 0 {
+			d = -d
+		}
+		return now.Add(d), nil
+	}
+	for _, layout := range timeLayouts {
+		if tm, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return tm, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s'", value)
+}
+
+// newFilter creates a filter from the command-line option values.
+func newFilter(level, since, until, caller, match string) (*filter, error) {
+	f := &filter{level: log.LevelDebug, caller: caller}
+	var err error
+	if level != "" {
+		if f.level, err = log.ParseLevel(level); err != nil {
+			return nil, err
+		}
+	}
+	now := time.Now()
+	if f.since, err = parseTime(since, now); err != nil {
+		return nil, err
+	}
+	if f.until, err = parseTime(until, now); err != nil {
+		return nil, err
+	}
+	if caller != "" {
+		// Check the pattern once, so that Match() can ignore errors.
+		if _, err = filepath.Match(caller, ""); err != nil {
+			return nil, err
+		}
+	}
+	if match != "" {
+		if f.match, err = regexp.Compile(match); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// Match checks, if the record passes all filter criteria.
+func (f *filter) Match(rec *log.Record) bool {
+	if rec.Level > f.level {
+		return false
+	}
+	if !f.since.IsZero() && rec.Time.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && rec.Time.After(f.until) {
+		return false
+	}
+	if f.caller != "" {
+		if ok, _ := filepath.Match(f.caller, rec.File); !ok {
+			return false
+		}
+	}
+	if f.match != nil && !f.match.MatchString(rec.Message) {
+		return false
+	}
+	return true
+}
+
+// parseLine parses a text or JSON encoded log line.
+func parseLine(line string) (*log.Record, error) {
+	if strings.HasPrefix(line, "{") {
+		rec := &log.Record{}
+		if err := json.Unmarshal([]byte(line), rec); err != nil {
+			return nil, err
+		}
+		// Reject levels, which cannot be rendered.
+		if rec.Level < log.LevelEmergency || rec.Level > log.LevelDebug {
+			return nil, fmt.Errorf("invalid log level %d", rec.Level)
+		}
+		return rec, nil
+	}
+	return log.ParseRecord(line)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"github.com/marcusva/gadget/log"
+	"github.com/marcusva/gadget/testing/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+var _logdata = `2018/01/02 10:00:00 DEBUG     [db.go:10] connecting
+2018/01/02 10:00:01 INFO      [db.go:22] connected to db01
+2018/01/02 10:30:00 WARNING   [http.go:81] slow request
+took 3.2s
+2018/01/02 11:00:00 ERROR     [http.go:93] request failed
+{"time":"2018-01-03T12:00:00Z","level":"CRITICAL","file":"db.go","line":50,"message":"connection lost"}
+`
+
+func readRecords(t *testing.T, f *filter) []*log.Record {
+	var records []*log.Record
+	reader := &recordReader{emit: func(rec *log.Record) {
+		if f.Match(rec) {
+			records = append(records, rec)
+		}
+	}}
+	assert.FailOnErr(t, readAll(strings.NewReader(_logdata), reader))
+	return records
+}
+
+func TestRecordReader(t *testing.T) {
+	f, err := newFilter("", "", "", "", "")
+	assert.FailOnErr(t, err)
+
+	records := readRecords(t, f)
+	assert.FailIfNot(t, len(records) == 5, "unexpected records: %v", records)
+	assert.Equal(t, records[2].Message, "slow request\ntook 3.2s")
+	assert.Equal(t, records[4].Level, log.LevelCritical)
+	assert.Equal(t, records[4].File, "db.go")
+}
+
+func TestFilter(t *testing.T) {
+	f, err := newFilter("Warning", "", "", "", "")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, len(readRecords(t, f)), 3)
+
+	f, err = newFilter("", "", "", "db.go", "")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, len(readRecords(t, f)), 3)
+
+	f, err = newFilter("", "", "", "", "^conn")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, len(readRecords(t, f)), 3)
+
+	f, err = newFilter("", "2018/01/02 10:00:01", "2018/01/02 11:00:00", "", "")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, len(readRecords(t, f)), 3)
+
+	invalid := [][]string{
+		{"verbose", "", "", "", ""},
+		{"", "yesterday", "", "", ""},
+		{"", "", "", "[", ""},
+		{"", "", "", "", "(unclosed"},
+	}
+	for _, args := range invalid {
+		_, err = newFilter(args[0], args[1], args[2], args[3], args[4])
+		assert.Err(t, err, "invalid filter %v was accepted", args)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Now()
+	tm, err := parseTime("2h", now)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, tm, now.Add(-2*time.Hour))
+
+	tm, err = parseTime("", now)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, tm.IsZero(), true)
+
+	tm, err = parseTime("2018-01-02T12:00:00Z", now)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, tm.Equal(time.Date(2018, 1, 2, 12, 0, 0, 0, time.UTC)), true)
+}
+
+func TestParseLineLevel(t *testing.T) {
+	_, err := parseLine(`{"time":"2018-01-03T12:00:00Z","level":42,"message":"invalid"}`)
+	assert.Err(t, err)
+	rec, err := parseLine(`{"time":"2018-01-03T12:00:00Z","level":"3","message":"valid"}`)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, rec.Level, log.LevelError)
+}
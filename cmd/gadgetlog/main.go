@@ -0,0 +1,191 @@
+// Command gadgetlog reads, filters and re-renders log files written by the
+// github.com/marcusva/gadget/log package.
+//
+// Usage:
+//
+//	gadgetlog [options] [file ...]
+//
+// If no file is given, the records are read from the standard input. Records
+// can be filtered by their minimum severity, a time range, the caller file and
+// a regular expression matching the message:
+//
+//	# Show all errors and more severe records of the last two hours
+//	gadgetlog -level Error -since 2h app.log
+//
+//	# Follow app.log, even if it gets rotated, and show the records as JSON
+//	gadgetlog -f -format json app.log
+//
+// Both, text records as written by the log package and JSON records as
+// written with -format json, are understood as input.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/marcusva/gadget/log"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// renderer writes records to an output stream.
+type renderer struct {
+	out       *bufio.Writer
+	formatter log.Formatter
+	json      bool
+}
+
+// Render writes a single record.
+func (r *renderer) Render(rec *log.Record) error {
+	if r.json {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		r.out.Write(data)
+		return r.out.WriteByte('\n')
+	}
+	_, err := r.out.Write(r.formatter.Format(rec))
+	return err
+}
+
+// colorMode gets the log.ColorMode for the -color option.
+func colorMode(mode string) (log.ColorMode, error) {
+	switch mode {
+	case "always":
+		return log.ColorAlways, nil
+	case "never":
+		return log.ColorNever, nil
+	case "auto":
+		return log.ColorAuto, nil
+	default:
+		return log.ColorNever, fmt.Errorf("invalid color mode '%s'", mode)
+	}
+}
+
+// tailBuffer keeps the last records passed to Add.
+type tailBuffer struct {
+	records []*log.Record
+	size    int
+}
+
+// Add adds a record, dropping the oldest one, if the buffer is full.
+func (t *tailBuffer) Add(rec *log.Record) {
+	if len(t.records) == t.size {
+		copy(t.records, t.records[1:])
+		t.records = t.records[:len(t.records)-1]
+	}
+	t.records = append(t.records, rec)
+}
+
+func run() error {
+	var (
+		level    = flag.String("level", "Debug", "minimum severity of the records to show")
+		since    = flag.String("since", "", "show records at or after this time or duration ago")
+		until    = flag.String("until", "", "show records at or before this time or duration ago")
+		caller   = flag.String("caller", "", "show records of caller files matching this glob pattern")
+		match    = flag.String("match", "", "show records with messages matching this regular expression")
+		format   = flag.String("format", "text", "output format, text or json")
+		color    = flag.String("color", "auto", "colored text output, auto, always or never")
+		follow   = flag.Bool("f", false, "follow the file, even if it gets rotated")
+		lines    = flag.Int("n", -1, "only show the last n records")
+		interval = flag.Duration("interval", time.Second, "poll interval for -f")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [options] [file ...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	filter, err := newFilter(*level, *since, *until, *caller, *match)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("invalid format '%s'", *format)
+	}
+	rd := &renderer{out: bufio.NewWriter(os.Stdout), json: *format == "json"}
+	mode, err := colorMode(*color)
+	if err != nil {
+		return err
+	}
+	rd.formatter = log.NewConsoleFormatter(os.Stdout, mode)
+	files := flag.Args()
+	if *follow && len(files) != 1 {
+		return errors.New("-f requires exactly one file")
+	}
+
+	var renderErr error
+	live := func(rec *log.Record) {
+		if renderErr == nil && filter.Match(rec) {
+			renderErr = rd.Render(rec)
+		}
+	}
+	tail := &tailBuffer{size: *lines}
+	reader := &recordReader{emit: live}
+	if *lines >= 0 {
+		reader.emit = func(rec *log.Record) {
+			if tail.size > 0 && filter.Match(rec) {
+				tail.Add(rec)
+			}
+		}
+	}
+	// flush writes the collected tail records and switches to the direct
+	// output of records.
+	flush := func() {
+		for _, rec := range tail.records {
+			if renderErr == nil {
+				renderErr = rd.Render(rec)
+			}
+		}
+		tail.records = nil
+		reader.emit = live
+		if renderErr == nil {
+			renderErr = rd.out.Flush()
+		}
+	}
+
+	if *follow {
+		stop := make(chan struct{})
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt)
+		go func() {
+			<-signals
+			close(stop)
+		}()
+		f := &follower{path: files[0], interval: *interval, idle: flush}
+		if err := f.Run(reader, stop); err != nil {
+			return err
+		}
+		return renderErr
+	}
+
+	if len(files) == 0 {
+		if err := readAll(os.Stdin, reader); err != nil {
+			return err
+		}
+	}
+	for _, fname := range files {
+		fp, err := os.Open(fname)
+		if err != nil {
+			return err
+		}
+		err = readAll(fp, reader)
+		fp.Close()
+		if err != nil {
+			return err
+		}
+	}
+	flush()
+	return renderErr
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[0], err)
+		os.Exit(1)
+	}
+}
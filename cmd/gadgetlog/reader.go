@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"github.com/marcusva/gadget/log"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// recordReader assembles records from log lines. Lines, which cannot be
+// parsed, are treated as continuation of the previous record, e.g. for
+// multi-line messages. Lines in front of the first record are skipped.
+type recordReader struct {
+	pending *log.Record
+	emit    func(*log.Record)
+}
+
+// Line processes a single line of input.
+func (r *recordReader) Line(line string) {
+	line = strings.TrimRight(line, "\r\n")
+	rec, err := parseLine(line)
+	if err != nil {
+		if r.pending != nil {
+			r.pending.Message += "\n" + line
+		}
+		return
+	}
+	r.Flush()
+	r.pending = rec
+}
+
+// Flush emits the currently assembled record, if any.
+func (r *recordReader) Flush() {
+	if r.pending != nil {
+		r.emit(r.pending)
+		r.pending = nil
+	}
+}
+
+// readAll reads all records from in.
+func readAll(in io.Reader, r *recordReader) error {
+	reader := bufio.NewReader(in)
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			r.Line(line)
+		}
+		if err == io.EOF {
+			r.Flush()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// follower continuously reads a file like "tail -F". If the file is
+// replaced, e.g. due to a log rotation, the rest of the old file is read,
+// before the new file will be opened and read from its beginning. If the file
+// is truncated, it will be read again from its beginning.
+type follower struct {
+	path     string
+	interval time.Duration
+	fp       *os.File
+	info     os.FileInfo
+	reader   *bufio.Reader
+	offset   int64
+	// replaced is true, if the file was replaced and the new one needs to be
+	// opened after reading the old one completely.
+	replaced bool
+	// idle is invoked, whenever all available content has been read.
+	idle func()
+}
+
+// open (re)opens the followed file.
+func (f *follower) open() error {
+	fp, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	info, err := fp.Stat()
+	if err != nil {
+		fp.Close()
+		return err
+	}
+	if f.fp != nil {
+		f.fp.Close()
+	}
+	f.fp, f.info, f.offset = fp, info, 0
+	f.reader = bufio.NewReader(fp)
+	return nil
+}
+
+// rotated checks, if the followed file was replaced or truncated. Truncated
+// files are rewound, replaced files are marked to be reopened.
+func (f *follower) rotated() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		// The file may be in the middle of a rotation, try again later.
+		return nil
+	}
+	if !os.SameFile(info, f.info) {
+		f.replaced = true
+		return nil
+	}
+	if info.Size() < f.offset {
+		if _, err := f.fp.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		f.offset = 0
+		f.reader.Reset(f.fp)
+	}
+	return nil
+}
+
+// Run reads the file from its beginning until stop is closed.
+func (f *follower) Run(r *recordReader, stop <-chan struct{}) error {
+	if err := f.open(); err != nil {
+		return err
+	}
+	defer func() { f.fp.Close() }()
+
+	partial := ""
+	for {
+		line, err := f.reader.ReadString('\n')
+		f.offset += int64(len(line))
+		partial += line
+		if err == nil {
+			r.Line(partial)
+			partial = ""
+			continue
+		}
+		if err != io.EOF {
+			return err
+		}
+		if f.replaced {
+			// The old file was read completely, continue with the new one.
+			if partial != "" {
+				r.Line(partial)
+				partial = ""
+			}
+			if err := f.open(); err != nil {
+				return err
+			}
+			f.replaced = false
+			continue
+		}
+		// Nothing more to read for now, show what we have and wait for new
+		// content.
+		r.Flush()
+		if f.idle != nil {
+			f.idle()
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(f.interval):
+		}
+		if err := f.rotated(); err != nil {
+			return err
+		}
+		if f.offset == 0 {
+			partial = ""
+		}
+	}
+}
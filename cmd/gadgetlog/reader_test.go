@@ -0,0 +1,47 @@
+package main
+
+import (
+	"github.com/marcusva/gadget/log"
+	"github.com/marcusva/gadget/testing/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFollowerRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gadgetlog")
+	assert.FailOnErr(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	assert.FailOnErr(t, ioutil.WriteFile(path, []byte("2018/01/02 10:00:00 INFO      first\n"), 0644))
+
+	var messages []string
+	reader := &recordReader{emit: func(rec *log.Record) {
+		messages = append(messages, rec.Message)
+	}}
+	stop := make(chan struct{})
+	idle := 0
+	f := &follower{path: path, interval: 10 * time.Millisecond}
+	f.idle = func() {
+		idle++
+		switch idle {
+		case 1:
+			// Write to the old file right before the rotation, which has to
+			// be read before the new file.
+			fp, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+			assert.FailOnErr(t, err)
+			fp.WriteString("2018/01/02 10:00:01 INFO      second\n")
+			fp.Close()
+			assert.FailOnErr(t, os.Rename(path, path+".1"))
+			assert.FailOnErr(t, ioutil.WriteFile(path, []byte("2018/01/02 10:00:02 INFO      third\n"), 0644))
+		default:
+			if len(messages) == 3 || idle > 100 {
+				close(stop)
+			}
+		}
+	}
+	assert.FailOnErr(t, f.Run(reader, stop))
+	assert.Equal(t, messages, []string{"first", "second", "third"})
+}
@@ -5,7 +5,6 @@ import (
 	"bufio"
 	"fmt"
 	"io"
-	"os"
 	"strconv"
 	"strings"
 )
@@ -16,6 +15,28 @@ type Config struct {
 	// Sections contains the individual sections of the configuration with
 	// their key-value pair mappings.
 	Sections map[string]map[string]string
+
+	// lines contains the lines of the loaded file to preserve comments,
+	// ordering and formatting on writing the Config.
+	lines []docLine
+	// added contains the keys added via Set, which are not part of lines, in
+	// the order of their addition.
+	added []sectionKey
+	// env maps the keys set via ApplyEnv to the environment variables.
+	env map[sectionKey]string
+	// opts are the Options used for loading the Config.
+	opts Options
+	// multi contains the values of keys defined multiple times with the
+	// DuplicateAccumulate policy or via key[].
+	multi map[sectionKey][]string
+	// lists contains the keys defined via key[].
+	lists map[sectionKey]bool
+	// subsections maps the names of git-style subsections, such as
+	// remote.origin for [remote "origin"], to the name of their parent.
+	subsections map[string]string
+	// patterns contains the include patterns with wildcards and the patterns
+	// of drop-in directories, whose matches may change after loading.
+	patterns []string
 }
 
 // Validator allows a Config to be checked for invalid configuration settings.
@@ -26,14 +47,15 @@ func NoValidate(cfg *Config) error { return nil }
 
 // Get gets a value for a certain key within the specified section.
 func (cfg *Config) Get(section, key string) (string, error) {
+	section, key = cfg.foldSection(section), cfg.fold(key)
 	opts, ok := cfg.Sections[section]
 	if !ok {
-		return "", fmt.Errorf("section '%s' does not exist", section)
+		return "", &NotFoundError{Section: section}
 	}
 	if v, ok := opts[key]; ok {
 		return v, nil
 	}
-	return "", fmt.Errorf("key '%s' not found in section '%s'", key, section)
+	return "", &NotFoundError{Section: section, Key: key}
 }
 
 // GetOrPanic gets a value for a certain key or panics.
@@ -56,20 +78,21 @@ func (cfg *Config) GetDefault(section, key, def string) string {
 
 // Int gets a value for a certain key within the specified section as int.
 func (cfg *Config) Int(section, key string) (int, error) {
-	val, err := cfg.Get(section, key)
-	if err != nil {
-		return -1, err
-	}
-	return strconv.Atoi(val)
+	v := -1
+	err := cfg.convert(section, key, func(val string) (err error) {
+		v, err = strconv.Atoi(val)
+		return err
+	})
+	return v, err
 }
 
 // Bool gets a value for a certain key within the specified section as bool.
-func (cfg *Config) Bool(section, key string) (bool, error) {
-	val, err := cfg.Get(section, key)
-	if err != nil {
-		return false, err
-	}
-	return strconv.ParseBool(val)
+func (cfg *Config) Bool(section, key string) (v bool, err error) {
+	err = cfg.convert(section, key, func(val string) error {
+		v, err = strconv.ParseBool(val)
+		return err
+	})
+	return v, err
 }
 
 // Array transforms a comma-separated value into a string array and returns
@@ -79,25 +102,47 @@ func (cfg *Config) Array(section, key string) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
+	return splitArray(val), nil
+}
+
+// Values gets all values for a certain key within the specified section.
+// Keys defined multiple times within a section are only kept, if they are
+// declared as key[] or with the DuplicateAccumulate policy, all other keys
+// have a single value.
+func (cfg *Config) Values(section, key string) ([]string, error) {
+	val, err := cfg.Get(section, key)
+	if err != nil {
+		return nil, err
+	}
+	if values, ok := cfg.multi[sectionKey{cfg.foldSection(section), cfg.fold(key)}]; ok {
+		return append([]string(nil), values...), nil
+	}
+	return []string{val}, nil
+}
+
+// splitArray splits a comma-separated value and removes the whitespace
+// around each value.
+func splitArray(val string) []string {
 	values := strings.Split(val, ",")
 	result := make([]string, len(values))
 	for idx, v := range values {
 		result[idx] = strings.TrimSpace(v)
 	}
-	return result, nil
+	return result
 }
 
 // HasSection checks, if the specified section exists within the Config.
 func (cfg *Config) HasSection(section string) bool {
-	_, ok := cfg.Sections[section]
+	_, ok := cfg.Sections[cfg.foldSection(section)]
 	return ok
 }
 
 // AllFor retrieves a map containing all options for the specified section.
 func (cfg *Config) AllFor(section string) (map[string]string, error) {
+	section = cfg.foldSection(section)
 	opts, ok := cfg.Sections[section]
 	if !ok {
-		return nil, fmt.Errorf("section '%s' does not exist", section)
+		return nil, &NotFoundError{Section: section}
 	}
 	result := make(map[string]string)
 	for k, v := range opts {
@@ -119,60 +164,180 @@ func (cfg *Config) AllFor(section string) (map[string]string, error) {
 //   # square brackets declare a new section
 //   [section]
 //   key = value
+//
+//   # subsections can be declared git-style as [remote "origin"] or
+//   # with dots as [remote.origin], both are available as "remote.origin"
+//   [remote "origin"]
+//   url = https://example.com/repo.git
+//
+//   # include other files, relative paths are resolved against the
+//   # directory of the including file
+//   !include other.ini
+//   !include conf.d/*.ini
+//
+// Included files are read at the position of the include directive, files
+// matching a pattern in lexical order. They have to declare their own
+// sections, but may extend sections of other files. A section declared
+// multiple times, also within the same file, is extended by each
+// declaration. If a key is defined multiple times, the last definition wins.
+//
+// Quoted values, inline comments and multi-line values can be enabled via
+// LoadFileOptions.
 func LoadFile(filename string, validator Validator) (*Config, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-	return Load(file, validator)
+	return LoadFileOptions(filename, Options{}, validator)
 }
 
-// Load loads the configuration from a io.Reader.
+// Load loads the configuration from a io.Reader. Relative paths of include
+// directives are resolved against the current working directory.
 func Load(r io.Reader, validate Validator) (*Config, error) {
-	cfg := &Config{
-		Sections: make(map[string]map[string]string),
+	return LoadOptions(r, Options{}, validate)
+}
+
+func newConfig() *Config {
+	return &Config{
+		Sections:    make(map[string]map[string]string),
+		multi:       make(map[sectionKey][]string),
+		lists:       make(map[sectionKey]bool),
+		subsections: make(map[string]string),
+	}
+}
+
+// parser reads the configuration of a single file into a Config.
+type parser struct {
+	cfg *Config
+	// file is the name of the parsed file, if any.
+	file string
+	// dir is the directory to resolve relative include paths against.
+	dir string
+	// included is true, if the file is included by another one.
+	included bool
+	// parents contains the files including the parsed file.
+	parents []string
+}
+
+// errorf creates a ParseError for the passed line and column of the parsed
+// file.
+func (p *parser) errorf(line, col int, format string, args ...interface{}) error {
+	return &ParseError{
+		Position: Position{File: p.file, Line: line, Column: col},
+		Err:      fmt.Errorf(format, args...),
 	}
+}
+
+func (p *parser) addLine(l docLine) {
+	l.file = p.file
+	l.included = p.included
+	p.cfg.lines = append(p.cfg.lines, l)
+}
 
-	offset := 0
+func (p *parser) parse(r io.Reader) error {
+	var lines []string
 	scanner := bufio.NewScanner(r)
-	var cursection string
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	cfg := p.cfg
+	opts := cfg.opts
+	seen := make(map[sectionKey]bool)
+	var cursection string
+	for idx := 0; idx < len(lines); idx++ {
+		raw := lines[idx]
+		line := strings.TrimSpace(raw)
 		llen := len(line)
-		offset++
+		offset := idx + 1
+		col := len(raw) - len(strings.TrimLeft(raw, " \t")) + 1
 		switch {
-		case llen == 0, line[0] == '#', line[0] == ';':
-			// Skip empty lines and comments
-			continue
+		case llen == 0:
+			p.addLine(docLine{kind: lineBlank, raw: raw, section: cursection, num: offset})
+		case line[0] == '#', line[0] == ';':
+			p.addLine(docLine{kind: lineComment, raw: raw, section: cursection, num: offset})
+		case isInclude(line):
+			p.addLine(docLine{kind: lineInclude, raw: raw, section: cursection, num: offset, col: col})
+			pattern := strings.TrimSpace(line[len(includeDirective):])
+			if err := p.include(offset, col, pattern); err != nil {
+				return err
+			}
 		case line[0] == '[' && line[llen-1] == ']':
-			cursection = strings.TrimSpace(line[1 : llen-1])
+			name, parent, err := cfg.sectionName(strings.TrimSpace(line[1 : llen-1]))
+			if err != nil {
+				return p.errorf(offset, col, "%w", err)
+			}
+			if parent != "" {
+				cfg.subsections[name] = parent
+			}
+			cursection = name
 			if len(cursection) == 0 {
-				return nil, fmt.Errorf("line %d: invalid, empty section name", offset)
+				return p.errorf(offset, col, "invalid, empty section name")
 			}
-			if _, ok := cfg.Sections[cursection]; ok {
-				// Section already exists, fail
-				return nil, fmt.Errorf("line %d: section '%s' was defined before", offset, cursection)
+			if _, ok := cfg.Sections[cursection]; !ok {
+				cfg.Sections[cursection] = make(map[string]string)
 			}
-			cfg.Sections[cursection] = make(map[string]string)
+			p.addLine(docLine{kind: lineSection, raw: raw, section: cursection, num: offset, col: col})
 		default:
-			if cursection == "" {
-				return nil, fmt.Errorf("line %d: key-value definition without section", offset)
+			section := cursection
+			if section == "" {
+				if opts.GlobalSection == "" {
+					return p.errorf(offset, col, "key-value definition without section")
+				}
+				section = cfg.fold(opts.GlobalSection)
+				if _, ok := cfg.Sections[section]; !ok {
+					cfg.Sections[section] = make(map[string]string)
+				}
 			}
 			kv := strings.SplitN(line, "=", 2)
 			if len(kv) < 2 {
-				return nil, fmt.Errorf("line %d: key-value definition misses assignment", offset)
+				return p.errorf(offset, col, "key-value definition misses assignment")
 			}
 			key := strings.TrimSpace(kv[0])
-			val := strings.TrimSpace(kv[1])
-			cfg.Sections[cursection][key] = val
+			// Keys of the form key[] are collected into a list.
+			list := strings.HasSuffix(key, "[]")
+			if list {
+				key = strings.TrimSpace(key[:len(key)-2])
+			}
+			key = cfg.fold(key)
+			val, tail, next, err := p.value(kv[1], lines, idx)
+			if err != nil {
+				return p.errorf(offset, col, "%w", err)
+			}
+			if next > idx {
+				raw = strings.Join(lines[idx:next+1], "\n")
+				idx = next
+			}
+			dl := docLine{
+				kind: lineKey, raw: raw, section: section, key: key, value: val,
+				tail: tail, num: offset, col: col,
+			}
+			sk := sectionKey{section, key}
+			if list {
+				cfg.lists[sk] = true
+			}
+			accumulate := cfg.accumulates(sk)
+			if seen[sk] {
+				switch {
+				case accumulate:
+					if _, ok := cfg.multi[sk]; !ok {
+						// The key was declared as plain key before.
+						cfg.multi[sk] = []string{cfg.Sections[section][key]}
+					}
+					cfg.multi[sk] = append(cfg.multi[sk], val)
+				case opts.Duplicates == DuplicateError:
+					return p.errorf(offset, col, "key '%s' was defined before in section '%s'", key, section)
+				case opts.Duplicates == DuplicateFirst:
+					dl.ignored = true
+				}
+			} else if accumulate {
+				cfg.multi[sk] = []string{val}
+			}
+			seen[sk] = true
+			if !dl.ignored {
+				cfg.Sections[section][key] = val
+			}
+			p.addLine(dl)
 		}
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-	if validate != nil {
-		return cfg, validate(cfg)
-	}
-	return cfg, nil
+	return nil
 }
@@ -48,13 +48,15 @@ func TestLoad(t *testing.T) {
 	_, err = config.Load(strings.NewReader(_broken2), config.NoValidate)
 	assert.Err(t, err)
 
-	_broken3 := `
+	// Sections declared twice are merged
+	_twice := `
 	[log]
 	level = Debug
 	[log]
 	level = Info`
-	_, err = config.Load(strings.NewReader(_broken3), config.NoValidate)
-	assert.Err(t, err)
+	cfg, err := config.Load(strings.NewReader(_twice), config.NoValidate)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, cfg.GetOrPanic("log", "level"), "Info")
 
 	_broken4 := `
 	[ ]
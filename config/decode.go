@@ -0,0 +1,406 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DecodeError is returned by Unmarshal, if a value could not be decoded.
+type DecodeError struct {
+	// Section is the section of the value.
+	Section string
+	// Key is the key of the value.
+	Key string
+	// File is the name of the file containing the value. It is empty, if the
+	// configuration was not loaded from a file.
+	File string
+	// Line is the line of the value within the file. It is 0, if the line is
+	// unknown, e.g. for missing values.
+	Line int
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	msg := fmt.Sprintf("section '%s', key '%s': %v", e.Section, e.Key, e.Err)
+	if e.Line > 0 {
+		msg = fmt.Sprintf("line %d: %s", e.Line, msg)
+		if e.File != "" {
+			msg = e.File + ": " + msg
+		}
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// fieldTag contains the parsed ini and default tags of a struct field.
+type fieldTag struct {
+	name string
+	// fold is true, if the name is the field name, which is matched
+	// case-insensitively.
+	fold       bool
+	required   bool
+	def        string
+	hasDefault bool
+}
+
+func parseTag(field reflect.StructField) (fieldTag, bool) {
+	tag := fieldTag{name: field.Name, fold: true}
+	value, ok := field.Tag.Lookup("ini")
+	if value == "-" {
+		return tag, false
+	}
+	if ok {
+		parts := strings.Split(value, ",")
+		if parts[0] != "" {
+			tag.name = parts[0]
+			tag.fold = false
+		}
+		for _, opt := range parts[1:] {
+			if strings.TrimSpace(opt) == "required" {
+				tag.required = true
+			}
+		}
+	}
+	tag.def, tag.hasDefault = field.Tag.Lookup("default")
+	return tag, true
+}
+
+// isSection checks, if the passed type is decoded as a whole section.
+func isSection(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// posOf gets the file and line of a key within the loaded configuration. The
+// file is empty, if the configuration was not loaded from a file. The line is
+// 0, if the key was not loaded. If the key was defined multiple times, the
+// position of the definition in effect is returned.
+func (cfg *Config) posOf(section, key string) (string, int) {
+	pos, _ := cfg.KeyPosition(section, key)
+	return pos.File, pos.Line
+}
+
+// decodeError creates a DecodeError at the position of the key.
+func (cfg *Config) decodeError(section, key string, err error) *DecodeError {
+	file, line := cfg.posOf(section, key)
+	return &DecodeError{Section: section, Key: key, File: file, Line: line, Err: err}
+}
+
+// posOfSection gets the file and line of the first declaration of a section
+// within the loaded configuration. The line is 0, if the section was not
+// loaded.
+func (cfg *Config) posOfSection(section string) (string, int) {
+	pos, _ := cfg.SectionPosition(section)
+	return pos.File, pos.Line
+}
+
+// sectionFold gets the name of the section matching the passed name
+// case-insensitively. An exact match is preferred.
+func (cfg *Config) sectionFold(name string) string {
+	if cfg.HasSection(name) {
+		return name
+	}
+	for section := range cfg.Sections {
+		if strings.EqualFold(section, name) {
+			return section
+		}
+	}
+	return name
+}
+
+// keyFold gets the name of the key matching the passed name
+// case-insensitively. An exact match is preferred.
+func (cfg *Config) keyFold(section, name string) string {
+	opts := cfg.Sections[cfg.foldSection(section)]
+	if _, ok := opts[cfg.fold(name)]; ok {
+		return name
+	}
+	for key := range opts {
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+	return name
+}
+
+// Unmarshal fills the struct pointed to by v with the values of the Config.
+// The fields of v are mapped via "ini" tags:
+//
+//	type Settings struct {
+//	    // A field with a "section.key" tag gets the value of the key
+//	    Port int `ini:"server.port,required"`
+//	    // A struct field gets all values of the section named by the tag
+//	    Log struct {
+//	        Level string        `ini:"level" default:"Info"`
+//	        Flush time.Duration `ini:"flush"`
+//	        Files []string      `ini:"files"`
+//	        Mode  *int          `ini:"mode"`
+//	    } `ini:"log"`
+//	    // A map of structs gets all subsections of the section, e.g.
+//	    // [backend "a"] and [backend "b"], keyed by their names
+//	    Backends map[string]Backend `ini:"backend"`
+//	}
+//
+// Struct and map fields within a section struct are read from the
+// subsections of the section.
+//
+// Fields without a tag use their field name as section or key, which is
+// matched case-insensitively. Fields of the top-level struct, which are
+// neither structs nor tagged with a "section.key" tag, are ignored as well as
+// fields tagged with "-".
+//
+// Supported field types are strings, booleans, all integer types, which are
+// read as decimal numbers, floating point types, time.Duration, types
+// implementing encoding.TextUnmarshaler, slices of those, which are read as
+// comma-separated values or from repeated keys as with List, maps with string
+// keys and values of those, which are read as key:value pairs as with Map,
+// and pointers to those. Pointers are only set, if the key exists or has a
+// default value, so that they can be used for optional values.
+//
+// The "default" tag provides the value to use, if the key does not exist.
+// The "required" option causes an error, if the key does not exist and has
+// no default value. All errors are of the type *DecodeError.
+func (cfg *Config) Unmarshal(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("Unmarshal requires a non-nil pointer to a struct")
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+	for idx := 0; idx < rt.NumField(); idx++ {
+		field := rt.Field(idx)
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+		tag, ok := parseTag(field)
+		if !ok {
+			continue
+		}
+		fv := rv.Field(idx)
+		if isSectionMap(field.Type) {
+			if err := cfg.decodeSections(tag.name, fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if isSection(field.Type) {
+			section := tag.name
+			if tag.fold {
+				section = cfg.sectionFold(section)
+			}
+			if err := cfg.decodeSection(section, fv); err != nil {
+				return err
+			}
+			continue
+		}
+		sep := strings.LastIndexByte(tag.name, '.')
+		if sep <= 0 {
+			continue
+		}
+		if err := cfg.decodeKey(tag.name[:sep], tag.name[sep+1:], tag, fv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeSection fills the struct value fv with the values of the section.
+func (cfg *Config) decodeSection(section string, fv reflect.Value) error {
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			if !cfg.HasSection(section) {
+				return nil
+			}
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		fv = fv.Elem()
+	}
+	ft := fv.Type()
+	for idx := 0; idx < ft.NumField(); idx++ {
+		field := ft.Field(idx)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag, ok := parseTag(field)
+		if !ok {
+			continue
+		}
+		switch {
+		case isSectionMap(field.Type):
+			if err := cfg.decodeSections(section+"."+tag.name, fv.Field(idx)); err != nil {
+				return err
+			}
+		case isSection(field.Type):
+			sub := section + "." + tag.name
+			if tag.fold {
+				sub = cfg.sectionFold(sub)
+			}
+			if err := cfg.decodeSection(sub, fv.Field(idx)); err != nil {
+				return err
+			}
+		default:
+			if err := cfg.decodeKey(section, tag.name, tag, fv.Field(idx)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decodeKey sets fv to the value of the key.
+func (cfg *Config) decodeKey(section, key string, tag fieldTag, fv reflect.Value) error {
+	if tag.fold {
+		key = cfg.keyFold(section, key)
+	}
+	val, err := cfg.Get(section, key)
+	if err != nil {
+		switch {
+		case tag.hasDefault:
+			val = tag.def
+		case tag.required:
+			return &DecodeError{Section: section, Key: key, Err: errors.New("required key is missing")}
+		default:
+			return nil
+		}
+	}
+	if err == nil && isSlice(fv.Type()) {
+		// Repeated keys provide one element per definition.
+		values, _ := cfg.Values(section, key)
+		if len(values) > 1 || cfg.lists[sectionKey{cfg.foldSection(section), cfg.fold(key)}] {
+			err = setSlice(fv, values)
+			if err != nil {
+				return cfg.decodeError(section, key, err)
+			}
+			return nil
+		}
+	}
+	if err := setValue(fv, val); err != nil {
+		return cfg.decodeError(section, key, err)
+	}
+	return nil
+}
+
+// isSlice checks, if the passed type is a slice or a pointer to a slice,
+// which is not decoded via encoding.TextUnmarshaler.
+func isSlice(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Slice && !reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// setSlice sets fv to a slice of the parsed values.
+func setSlice(fv reflect.Value, values []string) error {
+	if fv.Kind() == reflect.Ptr {
+		ptr := reflect.New(fv.Type().Elem())
+		if err := setSlice(ptr.Elem(), values); err != nil {
+			return err
+		}
+		fv.Set(ptr)
+		return nil
+	}
+	slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+	for idx, v := range values {
+		if err := setValue(slice.Index(idx), v); err != nil {
+			return err
+		}
+	}
+	fv.Set(slice)
+	return nil
+}
+
+// setValue parses val into fv.
+func setValue(fv reflect.Value, val string) error {
+	if fv.Kind() == reflect.Ptr {
+		ptr := reflect.New(fv.Type().Elem())
+		if err := setValue(ptr.Elem(), val); err != nil {
+			return err
+		}
+		fv.Set(ptr)
+		return nil
+	}
+	if fv.CanAddr() && fv.Addr().Type().Implements(textUnmarshalerType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+	}
+	if fv.Type() == durationType {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		values, err := listElements(val)
+		if err != nil {
+			return err
+		}
+		return setSlice(fv, values)
+	case reflect.Map:
+		if fv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", fv.Type())
+		}
+		pairs, err := mapElements(val)
+		if err != nil {
+			return err
+		}
+		m := reflect.MakeMapWithSize(fv.Type(), len(pairs))
+		for k, v := range pairs {
+			ev := reflect.New(fv.Type().Elem()).Elem()
+			if err := setValue(ev, v); err != nil {
+				return fmt.Errorf("map key '%s': %v", k, err)
+			}
+			m.SetMapIndex(reflect.ValueOf(k).Convert(fv.Type().Key()), ev)
+		}
+		fv.Set(m)
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package config_test
+
+import (
+	"github.com/marcusva/gadget/config"
+	"github.com/marcusva/gadget/testing/assert"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+var _decodecfg = `
+[server]
+host = localhost
+port = 8080
+timeout = 2m30s
+addr = 127.0.0.1
+ratio = 0.75
+tags = a, b,c
+
+[log]
+level = Debug
+sizes = 1, 2, 010
+verbose = true
+`
+
+type logSettings struct {
+	Level   string
+	File    string  `ini:"file" default:"/var/log/app.log"`
+	Sizes   []int64 `ini:"sizes"`
+	Verbose *bool   `ini:"verbose"`
+	Missing *int    `ini:"missing"`
+	Ignored string  `ini:"-"`
+}
+
+type settings struct {
+	Host    string        `ini:"server.host,required"`
+	Port    uint16        `ini:"server.port"`
+	Timeout time.Duration `ini:"server.timeout"`
+	Addr    net.IP        `ini:"server.addr"`
+	Ratio   float32       `ini:"server.ratio"`
+	Tags    []string      `ini:"server.tags"`
+	Retries int           `ini:"server.retries" default:"3"`
+	Log     logSettings   `ini:"log"`
+	DB      *struct {
+		Host string `ini:"host"`
+	} `ini:"db"`
+	Untagged string
+	private  string
+}
+
+func TestUnmarshal(t *testing.T) {
+	cfg, err := config.Load(strings.NewReader(_decodecfg), config.NoValidate)
+	assert.FailOnErr(t, err)
+
+	var s settings
+	assert.FailOnErr(t, cfg.Unmarshal(&s))
+	assert.Equal(t, s.Host, "localhost")
+	assert.Equal(t, s.Port, uint16(8080))
+	assert.Equal(t, s.Timeout, 150*time.Second)
+	assert.Equal(t, s.Addr.String(), "127.0.0.1")
+	assert.Equal(t, s.Ratio, float32(0.75))
+	assert.Equal(t, s.Tags, []string{"a", "b", "c"})
+	assert.Equal(t, s.Retries, 3)
+	assert.Equal(t, s.Log.Level, "Debug")
+	assert.Equal(t, s.Log.File, "/var/log/app.log")
+	assert.Equal(t, s.Log.Sizes, []int64{1, 2, 10})
+	assert.FailIf(t, s.Log.Verbose == nil || !*s.Log.Verbose, "verbose not set")
+	assert.FailIf(t, s.Log.Missing != nil, "missing is set")
+	assert.FailIf(t, s.DB != nil, "missing section is set")
+	assert.Equal(t, s.Untagged, "")
+
+	assert.Err(t, cfg.Unmarshal(s))
+	assert.Err(t, cfg.Unmarshal(nil))
+	var i int
+	assert.Err(t, cfg.Unmarshal(&i))
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	cfg, err := config.Load(strings.NewReader(_decodecfg), config.NoValidate)
+	assert.FailOnErr(t, err)
+
+	var invalid struct {
+		Port int8 `ini:"server.port"`
+	}
+	err = cfg.Unmarshal(&invalid)
+	assert.Err(t, err)
+	derr, ok := err.(*config.DecodeError)
+	assert.FailIfNot(t, ok, "invalid error type %T", err)
+	assert.Equal(t, derr.Section, "server")
+	assert.Equal(t, derr.Key, "port")
+	assert.Equal(t, derr.Line, 4)
+	assert.FailIfNot(t, strings.HasPrefix(err.Error(), "line 4: section 'server', key 'port': "),
+		"unexpected error: %v", err)
+
+	// Integers are decimal as with the getters of the Config
+	hex, err := config.Load(strings.NewReader("[a]\nhex = 0x10\n"), nil)
+	assert.FailOnErr(t, err)
+	var number struct {
+		Hex uint `ini:"a.hex"`
+	}
+	assert.Err(t, hex.Unmarshal(&number))
+
+	var required struct {
+		Log struct {
+			User string `ini:"user,required"`
+		} `ini:"log"`
+	}
+	err = cfg.Unmarshal(&required)
+	assert.Equal(t, err.Error(), "section 'log', key 'user': required key is missing")
+
+	var unsupported struct {
+		Level map[int]string `ini:"log.level"`
+	}
+	assert.Err(t, cfg.Unmarshal(&unsupported))
+}
+
+func TestUnmarshalListsAndMaps(t *testing.T) {
+	data := `[a]
+quoted = x, "y, z", 'w'
+empty =
+weights = a:1, "b:c": 2
+names = O'Brien, don't
+owners = a:O'Brien, b's:x
+timeouts = read:1s, write:2m
+badmap = a:x
+`
+	cfg, err := config.Load(strings.NewReader(data), nil)
+	assert.FailOnErr(t, err)
+
+	var s struct {
+		Quoted   []string                  `ini:"a.quoted"`
+		Empty    []string                  `ini:"a.empty"`
+		Weights  map[string]int            `ini:"a.weights"`
+		Timeouts *map[string]time.Duration `ini:"a.timeouts"`
+		NoMap    map[string]string         `ini:"a.empty"`
+		Names    []string                  `ini:"a.names"`
+		Owners   map[string]string         `ini:"a.owners"`
+	}
+	assert.FailOnErr(t, cfg.Unmarshal(&s))
+	assert.Equal(t, s.Quoted, []string{"x", "y, z", "w"})
+	assert.Equal(t, len(s.Empty), 0)
+	assert.Equal(t, s.Weights, map[string]int{"a": 1, "b:c": 2})
+	assert.NotNil(t, s.Timeouts)
+	assert.Equal(t, *s.Timeouts, map[string]time.Duration{"read": time.Second, "write": 2 * time.Minute})
+	assert.Equal(t, len(s.NoMap), 0)
+	assert.Equal(t, s.Names, []string{"O'Brien", "don't"})
+	assert.Equal(t, s.Owners, map[string]string{"a": "O'Brien", "b's": "x"})
+
+	var bad struct {
+		Map map[string]int `ini:"a.badmap"`
+	}
+	err = cfg.Unmarshal(&bad)
+	assert.Err(t, err)
+	assert.FailIfNot(t, strings.Contains(err.Error(), "map key 'a'"), "unexpected error: %v", err)
+}
+
+func TestUnmarshalCaseInsensitive(t *testing.T) {
+	data := "[Server]\nHost = localhost\nPORT = 8080\n"
+	cfg, err := config.LoadOptions(strings.NewReader(data), config.Options{CaseInsensitive: true}, nil)
+	assert.FailOnErr(t, err)
+
+	var s struct {
+		Server struct {
+			Host string
+			Port int
+		}
+		Timeout time.Duration `ini:"SERVER.Timeout" default:"1m"`
+	}
+	assert.FailOnErr(t, cfg.Unmarshal(&s))
+	assert.Equal(t, s.Server.Host, "localhost")
+	assert.Equal(t, s.Server.Port, 8080)
+	assert.Equal(t, s.Timeout, time.Minute)
+}
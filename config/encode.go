@@ -0,0 +1,324 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+type encodedKey struct {
+	key   string
+	value string
+	doc   string
+	unset bool
+}
+
+type encodedSection struct {
+	name string
+	doc  string
+	keys []encodedKey
+	// parent is the name of the parent section of git-style subsections,
+	// which are written as [parent "name"].
+	parent string
+}
+
+// encoder collects the sections and keys in the order of the struct fields.
+type encoder struct {
+	sections []*encodedSection
+}
+
+func (enc *encoder) section(name string) *encodedSection {
+	for _, sec := range enc.sections {
+		if sec.name == name {
+			return sec
+		}
+	}
+	sec := &encodedSection{name: name}
+	enc.sections = append(enc.sections, sec)
+	return sec
+}
+
+// Marshal creates a Config from the struct v or a pointer to it. The fields
+// are mapped via "ini" tags in the same way as for Unmarshal. The "doc" tag
+// of a field is written as comment in front of the key or, for struct fields,
+// the section:
+//
+//	type Settings struct {
+//	    Port int `ini:"server.port" doc:"The port to listen on"`
+//	    Log  struct {
+//	        Level string `ini:"level" doc:"Emergency, ..., Debug"`
+//	    } `ini:"log" doc:"Logging settings"`
+//	}
+//
+// Nil pointers are written as commented-out key with their default value, so
+// that optional values can be documented. Sections and keys are written in
+// the order of the struct fields. Nested structs are written as [parent.name]
+// sections, maps of structs as [parent "key"] sections for each map key in
+// sorted order.
+func Marshal(v interface{}) (*Config, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, errors.New("Marshal requires a struct or a non-nil pointer to a struct")
+	}
+	enc := &encoder{}
+	rt := rv.Type()
+	for idx := 0; idx < rt.NumField(); idx++ {
+		field := rt.Field(idx)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag, ok := parseTag(field)
+		if !ok {
+			continue
+		}
+		fv := rv.Field(idx)
+		if isSectionMap(field.Type) {
+			if err := enc.encodeSections(tag.name, field.Tag.Get("doc"), fv); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if isSection(field.Type) {
+			sec := enc.section(tag.name)
+			sec.doc = field.Tag.Get("doc")
+			if err := enc.encodeSection(sec, fv); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		sep := strings.LastIndexByte(tag.name, '.')
+		if sep <= 0 {
+			continue
+		}
+		sec := enc.section(tag.name[:sep])
+		if err := sec.encodeKey(tag.name[sep+1:], tag, field.Tag.Get("doc"), fv); err != nil {
+			return nil, err
+		}
+	}
+	return enc.config(), nil
+}
+
+// MarshalINI creates the INI representation of the struct v or a pointer to
+// it as described for Marshal.
+func MarshalINI(v interface{}) ([]byte, error) {
+	cfg, err := Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if _, err := cfg.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (enc *encoder) encodeSection(sec *encodedSection, fv reflect.Value) error {
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			fv = reflect.New(fv.Type().Elem())
+		}
+		fv = fv.Elem()
+	}
+	ft := fv.Type()
+	for idx := 0; idx < ft.NumField(); idx++ {
+		field := ft.Field(idx)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag, ok := parseTag(field)
+		if !ok {
+			continue
+		}
+		switch {
+		case isSectionMap(field.Type):
+			if err := enc.encodeSections(sec.name+"."+tag.name, field.Tag.Get("doc"), fv.Field(idx)); err != nil {
+				return err
+			}
+		case isSection(field.Type):
+			if strings.ContainsRune(sec.name, '"') {
+				return fmt.Errorf("section '%s': nested section '%s' cannot be written", sec.name, tag.name)
+			}
+			sub := enc.section(sec.name + "." + tag.name)
+			sub.doc = field.Tag.Get("doc")
+			if err := enc.encodeSection(sub, fv.Field(idx)); err != nil {
+				return err
+			}
+		default:
+			if err := sec.encodeKey(tag.name, tag, field.Tag.Get("doc"), fv.Field(idx)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// encodeSections writes the values of the map fv as git-style subsections
+// of parent in the order of the map keys.
+func (enc *encoder) encodeSections(parent, doc string, fv reflect.Value) error {
+	keys := fv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	for _, key := range keys {
+		name := key.String()
+		if name == "" || strings.ContainsAny(name, "\r\n") {
+			return fmt.Errorf("section '%s': invalid subsection name '%s'", parent, name)
+		}
+		sec := enc.section(parent + "." + name)
+		sec.parent = parent
+		sec.doc = doc
+		if err := enc.encodeSection(sec, fv.MapIndex(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sec *encodedSection) encodeKey(key string, tag fieldTag, doc string, fv reflect.Value) error {
+	ekey := encodedKey{key: key, doc: doc}
+	if fv.Kind() == reflect.Ptr && fv.IsNil() {
+		ekey.value = tag.def
+		ekey.unset = true
+	} else {
+		value, err := formatValue(fv)
+		if err != nil {
+			return fmt.Errorf("section '%s', key '%s': %v", sec.name, key, err)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("section '%s', key '%s': value contains a line break", sec.name, key)
+		}
+		ekey.value = value
+	}
+	sec.keys = append(sec.keys, ekey)
+	return nil
+}
+
+// formatValue formats fv as string.
+func formatValue(fv reflect.Value) (string, error) {
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return "", errors.New("nil pointer cannot be written")
+		}
+		return formatValue(fv.Elem())
+	}
+	if fv.Type().Implements(textMarshalerType) {
+		text, err := fv.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	if fv.CanAddr() && fv.Addr().Type().Implements(textMarshalerType) {
+		text, err := fv.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	if fv.Type() == durationType {
+		return time.Duration(fv.Int()).String(), nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		return fv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(fv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(fv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(fv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(fv.Float(), 'g', -1, fv.Type().Bits()), nil
+	case reflect.Slice:
+		values := make([]string, fv.Len())
+		for idx := range values {
+			value, err := formatValue(fv.Index(idx))
+			if err != nil {
+				return "", err
+			}
+			if strings.Contains(value, ",") {
+				return "", fmt.Errorf("slice value '%s' contains a comma", value)
+			}
+			values[idx] = value
+		}
+		return strings.Join(values, ", "), nil
+	case reflect.Map:
+		if fv.Type().Key().Kind() != reflect.String {
+			return "", fmt.Errorf("unsupported type %s", fv.Type())
+		}
+		keys := fv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		pairs := make([]string, len(keys))
+		for idx, key := range keys {
+			value, err := formatValue(fv.MapIndex(key))
+			if err != nil {
+				return "", err
+			}
+			pairs[idx] = quoteElement(key.String()) + ": " + quoteElement(value)
+		}
+		return strings.Join(pairs, ", "), nil
+	default:
+		return "", fmt.Errorf("unsupported type %s", fv.Type())
+	}
+}
+
+// quoteElement quotes an element of a map value, if it would not be read back
+// as is by Map.
+func quoteElement(elem string) string {
+	if elem == "" || elem != strings.TrimSpace(elem) || strings.ContainsAny(elem, ",:") ||
+		elem[0] == '"' || elem[0] == '\'' {
+		return strconv.Quote(elem)
+	}
+	return elem
+}
+
+// comments creates the comment lines for a doc tag.
+func comments(doc, section string) []docLine {
+	if doc == "" {
+		return nil
+	}
+	var lines []docLine
+	for _, text := range strings.Split(doc, "\n") {
+		lines = append(lines, docLine{kind: lineComment, raw: strings.TrimRight("# "+text, " "), section: section})
+	}
+	return lines
+}
+
+// config creates the Config with all collected sections and keys.
+func (enc *encoder) config() *Config {
+	cfg := newConfig()
+	for idx, sec := range enc.sections {
+		if idx > 0 {
+			cfg.lines = append(cfg.lines, docLine{kind: lineBlank, section: enc.sections[idx-1].name})
+		}
+		cfg.lines = append(cfg.lines, comments(sec.doc, sec.name)...)
+		header := "[" + sec.name + "]"
+		if sec.parent != "" {
+			cfg.subsections[sec.name] = sec.parent
+			name := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(sec.name[len(sec.parent)+1:])
+			header = "[" + sec.parent + ` "` + name + `"]`
+		}
+		cfg.lines = append(cfg.lines, docLine{kind: lineSection, raw: header, section: sec.name})
+		opts := make(map[string]string)
+		for _, key := range sec.keys {
+			cfg.lines = append(cfg.lines, comments(key.doc, sec.name)...)
+			if key.unset {
+				cfg.lines = append(cfg.lines, docLine{
+					kind: lineComment, raw: "# " + key.key + " = " + key.value, section: sec.name,
+				})
+				continue
+			}
+			opts[key.key] = key.value
+			cfg.lines = append(cfg.lines, docLine{
+				kind: lineKey, raw: key.key + " = " + key.value, section: sec.name,
+				key: key.key, value: key.value,
+			})
+		}
+		cfg.Sections[sec.name] = opts
+	}
+	return cfg
+}
@@ -0,0 +1,146 @@
+package config_test
+
+import (
+	"bytes"
+	"github.com/marcusva/gadget/config"
+	"github.com/marcusva/gadget/testing/assert"
+	"net"
+	"testing"
+	"time"
+)
+
+type sample struct {
+	Host    string        `ini:"server.host" doc:"The host name to listen on"`
+	Port    int           `ini:"server.port"`
+	Timeout time.Duration `ini:"server.timeout"`
+	Addr    net.IP        `ini:"server.addr"`
+	Log     struct {
+		Level string   `ini:"level" doc:"One of\nEmergency, ..., Debug"`
+		Files []string `ini:"files"`
+		Ratio float64  `ini:"ratio"`
+		Limit *uint    `ini:"limit" default:"100" doc:"Optional record limit"`
+		Sync  *bool    `ini:"sync"`
+	} `ini:"log" doc:"Logging settings"`
+	Ignored  string `ini:"-"`
+	Untagged int
+}
+
+func TestMarshalINI(t *testing.T) {
+	s := sample{
+		Host:    "localhost",
+		Port:    8080,
+		Timeout: 90 * time.Second,
+		Addr:    net.ParseIP("10.0.0.1"),
+	}
+	s.Log.Level = "Info"
+	s.Log.Files = []string{"a.log", "b.log"}
+	s.Log.Ratio = 0.5
+	sync := true
+	s.Log.Sync = &sync
+
+	data, err := config.MarshalINI(&s)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, string(data), `[server]
+# The host name to listen on
+host = localhost
+port = 8080
+timeout = 1m30s
+addr = 10.0.0.1
+
+# Logging settings
+[log]
+# One of
+# Emergency, ..., Debug
+level = Info
+files = a.log, b.log
+ratio = 0.5
+# Optional record limit
+# limit = 100
+sync = true
+`)
+}
+
+func TestMarshal(t *testing.T) {
+	s := sample{Host: "localhost", Port: 8080}
+	s.Log.Files = []string{"a.log"}
+
+	cfg, err := config.Marshal(s)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, cfg.GetDefault("server", "host", ""), "localhost")
+	_, err = cfg.Get("log", "limit")
+	assert.Err(t, err)
+
+	// Marshal and Unmarshal are symmetric
+	var s2 sample
+	assert.FailOnErr(t, cfg.Unmarshal(&s2))
+	assert.Equal(t, s2.Host, s.Host)
+	assert.Equal(t, s2.Port, s.Port)
+	assert.Equal(t, s2.Log.Files, s.Log.Files)
+	assert.Equal(t, *s2.Log.Limit, uint(100))
+
+	_, err = config.Marshal(nil)
+	assert.Err(t, err)
+	_, err = config.Marshal(12)
+	assert.Err(t, err)
+
+	_, err = config.Marshal(struct {
+		Value string `ini:"a.b"`
+	}{"line\nbreak"})
+	assert.Err(t, err)
+	_, err = config.Marshal(struct {
+		Values []string `ini:"a.b"`
+	}{[]string{"a,b"}})
+	assert.Err(t, err)
+	_, err = config.Marshal(struct {
+		Values map[int]int `ini:"a.b"`
+	}{})
+	assert.Err(t, err)
+	one := 1
+	_, err = config.Marshal(struct {
+		Values []*int `ini:"a.b"`
+	}{[]*int{&one, nil}})
+	assert.Err(t, err)
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	type backend struct {
+		URL string `ini:"url"`
+		TLS struct {
+			Verify bool `ini:"verify"`
+		} `ini:"tls"`
+	}
+	type settings struct {
+		Server struct {
+			Port int `ini:"port"`
+			HTTP struct {
+				Headers map[string]string `ini:"headers"`
+				Limits  map[string]int    `ini:"limits"`
+			} `ini:"http"`
+		} `ini:"server"`
+		Backends map[string]backend `ini:"backend"`
+	}
+	var in settings
+	in.Server.Port = 8080
+	in.Server.HTTP.Headers = map[string]string{"X-Name": "O'Brien", "X-List": "a, b", "X-Time": "12:00"}
+	in.Server.HTTP.Limits = map[string]int{"get": 10, "post": 2}
+	in.Backends = map[string]backend{"main": {URL: "http://a"}, `b \ one`: {URL: "http://b"}}
+	b := in.Backends["main"]
+	b.TLS.Verify = true
+	in.Backends["main"] = b
+
+	data, err := config.MarshalINI(&in)
+	assert.FailOnErr(t, err)
+	cfg, err := config.Load(bytes.NewReader(data), nil)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, cfg.GetOrPanic("server.http", "limits"), "get: 10, post: 2")
+	assert.Equal(t, cfg.Subsections("backend"), []string{"b \\ one", "main"})
+
+	var out settings
+	assert.FailOnErr(t, cfg.Unmarshal(&out))
+	assert.Equal(t, out, in)
+
+	// Nested sections of subsections with quotes cannot be written.
+	in.Backends[`"b"`] = backend{}
+	_, err = config.Marshal(&in)
+	assert.Err(t, err)
+}
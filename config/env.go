@@ -0,0 +1,141 @@
+package config
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// EnvOptions configures, how values of a Config are overridden by
+// environment variables via ApplyEnv.
+type EnvOptions struct {
+	// Prefix is prepended to all variable names, e.g. "APP" for APP_DB_HOST.
+	Prefix string
+	// Separator separates the prefix, section and key within the variable
+	// name. If it is empty, "_" will be used.
+	Separator string
+	// Name creates the variable name for a section and key. If it is nil,
+	// the prefix, section and key are joined by the separator and converted
+	// to upper case. All characters, which are not letters or digits, are
+	// replaced by "_".
+	Name func(section, key string) string
+	// Create enables the creation of sections and keys, which do not exist in
+	// the Config, from all variables starting with the prefix and separator,
+	// which do not belong to an existing key. The remainder of the variable
+	// name is split at the first separator into the section and key, which
+	// are converted to lower case. Create requires a Prefix.
+	Create bool
+}
+
+// EnvOverride describes a value, which was taken from an environment
+// variable.
+type EnvOverride struct {
+	Section  string
+	Key      string
+	Variable string
+}
+
+func (opts *EnvOptions) separator() string {
+	if opts.Separator == "" {
+		return "_"
+	}
+	return opts.Separator
+}
+
+// name creates the variable name for a section and key.
+func (opts *EnvOptions) name(section, key string) string {
+	if opts.Name != nil {
+		return opts.Name(section, key)
+	}
+	sep := opts.separator()
+	name := section + sep + key
+	if opts.Prefix != "" {
+		name = opts.Prefix + sep + name
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case strings.ContainsRune(sep, r):
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// ApplyEnv overrides the values of the Config with the values of environment
+// variables. For each existing key, the variable name is created as
+// configured by opts. If the variable is set, its value replaces the value
+// of the key:
+//
+//	# [db]
+//	# host = localhost
+//	cfg.ApplyEnv(config.EnvOptions{Prefix: "APP"})  // uses APP_DB_HOST
+//
+// With opts.Create enabled, new sections and keys are created for all
+// variables starting with the prefix. ApplyEnv returns the overridden and
+// created values sorted by section and key. The values can also be queried
+// via FromEnv later on. Note that the values are part of the Config and
+// will be written by WriteTo.
+func (cfg *Config) ApplyEnv(opts EnvOptions) []EnvOverride {
+	if cfg.Sections == nil {
+		cfg.Sections = make(map[string]map[string]string)
+	}
+	if cfg.env == nil {
+		cfg.env = make(map[sectionKey]string)
+	}
+	var result []EnvOverride
+	// known contains the variable names of the existing keys.
+	known := make(map[string]bool)
+	for section, values := range cfg.Sections {
+		for key := range values {
+			variable := opts.name(section, key)
+			known[variable] = true
+			if value, ok := os.LookupEnv(variable); ok {
+				values[key] = value
+				// The variable replaces all values of repeated keys.
+				delete(cfg.multi, sectionKey{section, key})
+				cfg.env[sectionKey{section, key}] = variable
+				result = append(result, EnvOverride{section, key, variable})
+			}
+		}
+	}
+
+	if opts.Create && opts.Prefix != "" {
+		sep := opts.separator()
+		prefix := opts.Prefix + sep
+		for _, env := range os.Environ() {
+			kv := strings.SplitN(env, "=", 2)
+			if len(kv) != 2 || !strings.HasPrefix(kv[0], prefix) || known[kv[0]] {
+				// Variables of existing keys were handled above.
+				continue
+			}
+			parts := strings.SplitN(kv[0][len(prefix):], sep, 2)
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				continue
+			}
+			section, key := strings.ToLower(parts[0]), strings.ToLower(parts[1])
+			cfg.Set(section, key, kv[1])
+			cfg.env[sectionKey{section, key}] = kv[0]
+			result = append(result, EnvOverride{section, key, kv[0]})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Section != result[j].Section {
+			return result[i].Section < result[j].Section
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// FromEnv checks, if the value of a key was taken from an environment
+// variable via ApplyEnv and returns the name of the variable.
+func (cfg *Config) FromEnv(section, key string) (string, bool) {
+	variable, ok := cfg.env[sectionKey{cfg.foldSection(section), cfg.fold(key)}]
+	return variable, ok
+}
@@ -0,0 +1,106 @@
+package config_test
+
+import (
+	"github.com/marcusva/gadget/config"
+	"github.com/marcusva/gadget/testing/assert"
+	"os"
+	"strings"
+	"testing"
+)
+
+func setenv(t *testing.T, vars map[string]string) func() {
+	for k, v := range vars {
+		assert.FailOnErr(t, os.Setenv(k, v))
+	}
+	return func() {
+		for k := range vars {
+			os.Unsetenv(k)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	defer setenv(t, map[string]string{
+		"GADGETTEST_DB_HOST":        "db01",
+		"GADGETTEST_LOG_LEVEL":      "Info",
+		"GADGETTEST_CACHE_SIZE_MAX": "100",
+		"GADGETTEST_INVALID":        "x",
+	})()
+
+	cfg, err := config.Load(strings.NewReader(`
+[db]
+host = localhost
+port = 5432
+[log]
+level = Debug`), config.NoValidate)
+	assert.FailOnErr(t, err)
+
+	result := cfg.ApplyEnv(config.EnvOptions{Prefix: "GADGETTEST"})
+	assert.Equal(t, result, []config.EnvOverride{
+		{Section: "db", Key: "host", Variable: "GADGETTEST_DB_HOST"},
+		{Section: "log", Key: "level", Variable: "GADGETTEST_LOG_LEVEL"},
+	})
+	assert.Equal(t, cfg.GetDefault("db", "host", ""), "db01")
+	assert.Equal(t, cfg.GetDefault("db", "port", ""), "5432")
+	assert.Equal(t, cfg.HasSection("cache"), false)
+
+	variable, ok := cfg.FromEnv("db", "host")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, variable, "GADGETTEST_DB_HOST")
+	_, ok = cfg.FromEnv("db", "port")
+	assert.Equal(t, ok, false)
+
+	result = cfg.ApplyEnv(config.EnvOptions{Prefix: "GADGETTEST", Create: true})
+	assert.Equal(t, len(result), 3)
+	assert.Equal(t, result[0], config.EnvOverride{
+		Section: "cache", Key: "size_max", Variable: "GADGETTEST_CACHE_SIZE_MAX",
+	})
+	assert.Equal(t, cfg.GetDefault("cache", "size_max", ""), "100")
+}
+
+func TestApplyEnvNaming(t *testing.T) {
+	defer setenv(t, map[string]string{
+		"GADGETTEST__SERVER_HTTP__LISTEN_ADDR": "0.0.0.0",
+		"server.http.port":                     "80",
+	})()
+
+	cfg := &config.Config{}
+	cfg.Set("server.http", "listen-addr", "localhost")
+	cfg.Set("server.http", "port", "8080")
+
+	result := cfg.ApplyEnv(config.EnvOptions{Prefix: "GADGETTEST", Separator: "__"})
+	assert.Equal(t, len(result), 1)
+	assert.Equal(t, cfg.GetDefault("server.http", "listen-addr", ""), "0.0.0.0")
+
+	result = cfg.ApplyEnv(config.EnvOptions{
+		Name: func(section, key string) string { return section + "." + key },
+	})
+	assert.Equal(t, len(result), 1)
+	assert.Equal(t, cfg.GetDefault("server.http", "port", ""), "80")
+}
+
+func TestApplyEnvCreateExisting(t *testing.T) {
+	defer setenv(t, map[string]string{
+		"GADGETTEST_SERVER_LISTEN_ADDR": "b",
+	})()
+
+	cfg, err := config.Load(strings.NewReader("[server]\nlisten-addr = a\n"), nil)
+	assert.FailOnErr(t, err)
+	result := cfg.ApplyEnv(config.EnvOptions{Prefix: "GADGETTEST", Create: true})
+	assert.Equal(t, result, []config.EnvOverride{
+		{Section: "server", Key: "listen-addr", Variable: "GADGETTEST_SERVER_LISTEN_ADDR"},
+	})
+	assert.Equal(t, cfg.Sections["server"], map[string]string{"listen-addr": "b"})
+}
+
+func TestApplyEnvList(t *testing.T) {
+	defer setenv(t, map[string]string{"GADGETTEST_A_SERVER": "z"})()
+
+	cfg, err := config.Load(strings.NewReader("[a]\nserver[] = x\nserver[] = y\n"), nil)
+	assert.FailOnErr(t, err)
+	cfg.ApplyEnv(config.EnvOptions{Prefix: "GADGETTEST"})
+	assert.Equal(t, cfg.GetOrPanic("a", "server"), "z")
+	values, err := cfg.List("a", "server")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, values, []string{"z"})
+}
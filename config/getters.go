@@ -0,0 +1,374 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValueError is returned by the typed getters, if a value cannot be
+// converted.
+type ValueError struct {
+	// Section is the section of the value.
+	Section string
+	// Key is the key of the value.
+	Key string
+	// Value is the raw value.
+	Value string
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *ValueError) Error() string {
+	return fmt.Sprintf("section '%s', key '%s': invalid value '%s': %v", e.Section, e.Key, e.Value, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ValueError) Unwrap() error {
+	return e.Err
+}
+
+// convert gets the value for a certain key and passes it to fn. Errors of fn
+// are returned as ValueError.
+func (cfg *Config) convert(section, key string, fn func(val string) error) error {
+	val, err := cfg.Get(section, key)
+	if err != nil {
+		return err
+	}
+	if err := fn(val); err != nil {
+		if ne, ok := err.(*strconv.NumError); ok {
+			// The value is part of the ValueError already.
+			err = ne.Err
+		}
+		return &ValueError{Section: section, Key: key, Value: val, Err: err}
+	}
+	return nil
+}
+
+// Float64 gets a value for a certain key within the specified section as
+// float64.
+func (cfg *Config) Float64(section, key string) (v float64, err error) {
+	err = cfg.convert(section, key, func(val string) error {
+		v, err = strconv.ParseFloat(val, 64)
+		return err
+	})
+	return v, err
+}
+
+// Float64Default gets a value for a certain key as float64. If the section or
+// key could not be found or the value is invalid, the provided default value
+// will be returned.
+func (cfg *Config) Float64Default(section, key string, def float64) float64 {
+	if v, err := cfg.Float64(section, key); err == nil {
+		return v
+	}
+	return def
+}
+
+// Int64 gets a value for a certain key within the specified section as
+// int64.
+func (cfg *Config) Int64(section, key string) (int64, error) {
+	return cfg.Int64Range(section, key, math.MinInt64, math.MaxInt64)
+}
+
+// Int64Default gets a value for a certain key as int64. If the section or
+// key could not be found or the value is invalid, the provided default value
+// will be returned.
+func (cfg *Config) Int64Default(section, key string, def int64) int64 {
+	if v, err := cfg.Int64(section, key); err == nil {
+		return v
+	}
+	return def
+}
+
+// Int64Range gets a value for a certain key as int64 like Int64 and checks,
+// if it is within the range [min, max].
+func (cfg *Config) Int64Range(section, key string, min, max int64) (v int64, err error) {
+	err = cfg.convert(section, key, func(val string) error {
+		if v, err = strconv.ParseInt(val, 10, 64); err != nil {
+			return err
+		}
+		if v < min || v > max {
+			return fmt.Errorf("value out of range [%d, %d]", min, max)
+		}
+		return nil
+	})
+	return v, err
+}
+
+// Uint64 gets a value for a certain key within the specified section as
+// uint64.
+func (cfg *Config) Uint64(section, key string) (uint64, error) {
+	return cfg.Uint64Range(section, key, 0, math.MaxUint64)
+}
+
+// Uint64Default gets a value for a certain key as uint64. If the section or
+// key could not be found or the value is invalid, the provided default value
+// will be returned.
+func (cfg *Config) Uint64Default(section, key string, def uint64) uint64 {
+	if v, err := cfg.Uint64(section, key); err == nil {
+		return v
+	}
+	return def
+}
+
+// Uint64Range gets a value for a certain key as uint64 like Uint64 and
+// checks, if it is within the range [min, max].
+func (cfg *Config) Uint64Range(section, key string, min, max uint64) (v uint64, err error) {
+	err = cfg.convert(section, key, func(val string) error {
+		if v, err = strconv.ParseUint(val, 10, 64); err != nil {
+			return err
+		}
+		if v < min || v > max {
+			return fmt.Errorf("value out of range [%d, %d]", min, max)
+		}
+		return nil
+	})
+	return v, err
+}
+
+// Duration gets a value for a certain key within the specified section as
+// time.Duration. The value has to be understood by time.ParseDuration, e.g.
+// "30s" or "1h30m".
+func (cfg *Config) Duration(section, key string) (v time.Duration, err error) {
+	err = cfg.convert(section, key, func(val string) error {
+		v, err = time.ParseDuration(val)
+		return err
+	})
+	return v, err
+}
+
+// DurationDefault gets a value for a certain key as time.Duration. If the
+// section or key could not be found or the value is invalid, the provided
+// default value will be returned.
+func (cfg *Config) DurationDefault(section, key string, def time.Duration) time.Duration {
+	if v, err := cfg.Duration(section, key); err == nil {
+		return v
+	}
+	return def
+}
+
+// byteUnits maps the lower-case units understood by ParseByteSize to their
+// factors.
+var byteUnits = map[string]uint64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1000,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1000 * 1000,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1000 * 1000 * 1000,
+	"gib": 1 << 30,
+	"t":   1 << 40,
+	"tb":  1000 * 1000 * 1000 * 1000,
+	"tib": 1 << 40,
+	"p":   1 << 50,
+	"pb":  1000 * 1000 * 1000 * 1000 * 1000,
+	"pib": 1 << 50,
+}
+
+// ParseByteSize parses a human-readable byte size, such as "512MiB", "1.5 GB"
+// or "64k". The units are case-insensitive. The SI units KB, MB, GB, TB and
+// PB are powers of 1000, the binary units KiB, MiB, GiB, TiB and PiB as well
+// as the short forms K, M, G, T and P are powers of 1024.
+func ParseByteSize(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if end == -1 {
+		end = len(s)
+	}
+	if end == 0 {
+		return 0, errors.New("missing number")
+	}
+	unit, ok := byteUnits[strings.ToLower(strings.TrimSpace(s[end:]))]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit '%s'", strings.TrimSpace(s[end:]))
+	}
+	number := s[:end]
+	if !strings.Contains(number, ".") {
+		v, err := strconv.ParseUint(number, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		if v > math.MaxUint64/unit {
+			return 0, strconv.ErrRange
+		}
+		return v * unit, nil
+	}
+	v, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, err
+	}
+	size := v * float64(unit)
+	if size >= math.MaxUint64 {
+		return 0, strconv.ErrRange
+	}
+	return uint64(size), nil
+}
+
+// ByteSize gets a value for a certain key within the specified section as
+// amount of bytes. The value has to be understood by ParseByteSize.
+func (cfg *Config) ByteSize(section, key string) (v uint64, err error) {
+	err = cfg.convert(section, key, func(val string) error {
+		v, err = ParseByteSize(val)
+		return err
+	})
+	return v, err
+}
+
+// ByteSizeDefault gets a value for a certain key as amount of bytes. If the
+// section or key could not be found or the value is invalid, the provided
+// default value will be returned.
+func (cfg *Config) ByteSizeDefault(section, key string, def uint64) uint64 {
+	if v, err := cfg.ByteSize(section, key); err == nil {
+		return v
+	}
+	return def
+}
+
+// Time gets a value for a certain key within the specified section as
+// time.Time using the passed layout for time.Parse.
+func (cfg *Config) Time(section, key, layout string) (v time.Time, err error) {
+	err = cfg.convert(section, key, func(val string) error {
+		v, err = time.Parse(layout, val)
+		return err
+	})
+	return v, err
+}
+
+// TimeDefault gets a value for a certain key as time.Time. If the section or
+// key could not be found or the value is invalid, the provided default value
+// will be returned.
+func (cfg *Config) TimeDefault(section, key, layout string, def time.Time) time.Time {
+	if v, err := cfg.Time(section, key, layout); err == nil {
+		return v
+	}
+	return def
+}
+
+// URL gets a value for a certain key within the specified section as
+// absolute URL.
+func (cfg *Config) URL(section, key string) (v *url.URL, err error) {
+	err = cfg.convert(section, key, func(val string) error {
+		if v, err = url.Parse(val); err != nil {
+			return err
+		}
+		if !v.IsAbs() {
+			return errors.New("URL is not absolute")
+		}
+		return nil
+	})
+	return v, err
+}
+
+// URLDefault gets a value for a certain key as absolute URL. If the section
+// or key could not be found or the value is invalid, the provided default
+// value will be returned.
+func (cfg *Config) URLDefault(section, key string, def *url.URL) *url.URL {
+	if v, err := cfg.URL(section, key); err == nil {
+		return v
+	}
+	return def
+}
+
+// IP gets a value for a certain key within the specified section as IPv4 or
+// IPv6 address.
+func (cfg *Config) IP(section, key string) (v net.IP, err error) {
+	err = cfg.convert(section, key, func(val string) error {
+		if v = net.ParseIP(val); v == nil {
+			return errors.New("invalid IP address")
+		}
+		return nil
+	})
+	return v, err
+}
+
+// IPDefault gets a value for a certain key as IP address. If the section or
+// key could not be found or the value is invalid, the provided default value
+// will be returned.
+func (cfg *Config) IPDefault(section, key string, def net.IP) net.IP {
+	if v, err := cfg.IP(section, key); err == nil {
+		return v
+	}
+	return def
+}
+
+// CIDR gets a value for a certain key within the specified section as
+// network in the CIDR notation, such as "192.168.0.0/16".
+func (cfg *Config) CIDR(section, key string) (v *net.IPNet, err error) {
+	err = cfg.convert(section, key, func(val string) error {
+		_, v, err = net.ParseCIDR(val)
+		return err
+	})
+	return v, err
+}
+
+// CIDRDefault gets a value for a certain key as network. If the section or
+// key could not be found or the value is invalid, the provided default value
+// will be returned.
+func (cfg *Config) CIDRDefault(section, key string, def *net.IPNet) *net.IPNet {
+	if v, err := cfg.CIDR(section, key); err == nil {
+		return v
+	}
+	return def
+}
+
+// FileMode gets a value for a certain key within the specified section as
+// octal permission bits, such as "0644". Only the permission bits are
+// supported.
+func (cfg *Config) FileMode(section, key string) (v os.FileMode, err error) {
+	err = cfg.convert(section, key, func(val string) error {
+		mode, err := strconv.ParseUint(val, 8, 32)
+		if err != nil {
+			return err
+		}
+		if mode&^uint64(os.ModePerm) != 0 {
+			return errors.New("invalid file mode")
+		}
+		v = os.FileMode(mode)
+		return nil
+	})
+	return v, err
+}
+
+// FileModeDefault gets a value for a certain key as file mode. If the section
+// or key could not be found or the value is invalid, the provided default
+// value will be returned.
+func (cfg *Config) FileModeDefault(section, key string, def os.FileMode) os.FileMode {
+	if v, err := cfg.FileMode(section, key); err == nil {
+		return v
+	}
+	return def
+}
+
+// Regexp gets a value for a certain key within the specified section as
+// compiled regular expression.
+func (cfg *Config) Regexp(section, key string) (v *regexp.Regexp, err error) {
+	err = cfg.convert(section, key, func(val string) error {
+		v, err = regexp.Compile(val)
+		return err
+	})
+	return v, err
+}
+
+// RegexpDefault gets a value for a certain key as regular expression. If the
+// section or key could not be found or the value is invalid, the provided
+// default value will be returned.
+func (cfg *Config) RegexpDefault(section, key string, def *regexp.Regexp) *regexp.Regexp {
+	if v, err := cfg.Regexp(section, key); err == nil {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,179 @@
+package config_test
+
+import (
+	"github.com/marcusva/gadget/config"
+	"github.com/marcusva/gadget/testing/assert"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+const _typed = `
+[values]
+float = 1.5
+int = -42
+uint = 42
+big = 18446744073709551615
+duration = 1m30s
+size = 512MiB
+sisize = 1.5 GB
+time = 2019-03-01
+url = https://example.com/path?q=1
+relurl = /path
+ip = 192.168.0.1
+ip6 = ::1
+cidr = 10.0.0.0/8
+mode = 0640
+regexp = ^a+b$
+invalid = abc
+`
+
+func loadTyped(t *testing.T) *config.Config {
+	cfg, err := config.Load(strings.NewReader(_typed), nil)
+	assert.FailOnErr(t, err)
+	return cfg
+}
+
+func TestValueError(t *testing.T) {
+	cfg := loadTyped(t)
+	_, err := cfg.Int64("values", "invalid")
+	assert.Err(t, err)
+	verr, ok := err.(*config.ValueError)
+	assert.FailIfNot(t, ok, "invalid error type %T", err)
+	assert.Equal(t, verr.Section, "values")
+	assert.Equal(t, verr.Key, "invalid")
+	assert.Equal(t, verr.Value, "abc")
+	assert.Equal(t, err.Error(), "section 'values', key 'invalid': invalid value 'abc': invalid syntax")
+
+	_, err = cfg.Int("values", "invalid")
+	_, ok = err.(*config.ValueError)
+	assert.FailIfNot(t, ok, "invalid error type %T", err)
+
+	// Missing keys are no ValueError
+	_, err = cfg.Int64("values", "missing")
+	assert.Err(t, err)
+	_, ok = err.(*config.ValueError)
+	assert.FailIf(t, ok, "invalid error type %T", err)
+}
+
+func TestNumbers(t *testing.T) {
+	cfg := loadTyped(t)
+
+	f, err := cfg.Float64("values", "float")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, f, 1.5)
+	assert.Equal(t, cfg.Float64Default("values", "invalid", 2.5), 2.5)
+
+	i, err := cfg.Int64("values", "int")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, i, int64(-42))
+	assert.Equal(t, cfg.Int64Default("values", "missing", 7), int64(7))
+	_, err = cfg.Int64("values", "big")
+	assert.Err(t, err)
+
+	i, err = cfg.Int64Range("values", "int", -50, 0)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, i, int64(-42))
+	_, err = cfg.Int64Range("values", "int", 0, 50)
+	assert.Err(t, err)
+
+	u, err := cfg.Uint64("values", "big")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, u, uint64(18446744073709551615))
+	_, err = cfg.Uint64("values", "int")
+	assert.Err(t, err)
+	assert.Equal(t, cfg.Uint64Default("values", "int", 3), uint64(3))
+	_, err = cfg.Uint64Range("values", "uint", 0, 10)
+	assert.Err(t, err)
+}
+
+func TestParseByteSize(t *testing.T) {
+	valid := map[string]uint64{
+		"0":       0,
+		"100":     100,
+		"100B":    100,
+		"64k":     64 << 10,
+		"1KB":     1000,
+		"1kib":    1024,
+		"512MiB":  512 << 20,
+		"1.5 GB":  1500000000,
+		"2G":      2 << 30,
+		"1TiB":    1 << 40,
+		"0.5 PiB": 1 << 49,
+	}
+	for s, expected := range valid {
+		v, err := config.ParseByteSize(s)
+		assert.FailOnErr(t, err)
+		assert.Equal(t, v, expected, s)
+	}
+	for _, s := range []string{"", "MB", "12XB", "1.2.3K", "20000000PB", "-1"} {
+		_, err := config.ParseByteSize(s)
+		assert.Err(t, err, s)
+	}
+}
+
+func TestTypedValues(t *testing.T) {
+	cfg := loadTyped(t)
+
+	d, err := cfg.Duration("values", "duration")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, d, 90*time.Second)
+	assert.Equal(t, cfg.DurationDefault("values", "invalid", time.Second), time.Second)
+
+	size, err := cfg.ByteSize("values", "size")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, size, uint64(512<<20))
+	assert.Equal(t, cfg.ByteSizeDefault("values", "sisize", 0), uint64(1500000000))
+
+	tm, err := cfg.Time("values", "time", "2006-01-02")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, tm, time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC))
+	_, err = cfg.Time("values", "time", time.RFC3339)
+	assert.Err(t, err)
+	assert.Equal(t, cfg.TimeDefault("values", "missing", "2006", tm), tm)
+
+	u, err := cfg.URL("values", "url")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, u.Host, "example.com")
+	_, err = cfg.URL("values", "relurl")
+	assert.Err(t, err)
+	def := &url.URL{Scheme: "http", Host: "localhost"}
+	assert.Equal(t, cfg.URLDefault("values", "relurl", def), def)
+
+	ip, err := cfg.IP("values", "ip")
+	assert.FailOnErr(t, err)
+	assert.FailIfNot(t, ip.Equal(net.IPv4(192, 168, 0, 1)))
+	ip, err = cfg.IP("values", "ip6")
+	assert.FailOnErr(t, err)
+	assert.FailIfNot(t, ip.Equal(net.IPv6loopback))
+	_, err = cfg.IP("values", "invalid")
+	assert.Err(t, err)
+	assert.Equal(t, cfg.IPDefault("values", "invalid", nil), net.IP(nil))
+
+	network, err := cfg.CIDR("values", "cidr")
+	assert.FailOnErr(t, err)
+	assert.FailIfNot(t, network.Contains(net.IPv4(10, 1, 2, 3)))
+	_, err = cfg.CIDR("values", "ip")
+	assert.Err(t, err)
+	assert.Equal(t, cfg.CIDRDefault("values", "ip", network), network)
+
+	mode, err := cfg.FileMode("values", "mode")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, mode, os.FileMode(0640))
+	_, err = cfg.FileMode("values", "big")
+	assert.Err(t, err)
+	assert.Equal(t, cfg.FileModeDefault("values", "int", 0600), os.FileMode(0600))
+
+	re, err := cfg.Regexp("values", "regexp")
+	assert.FailOnErr(t, err)
+	assert.FailIfNot(t, re.MatchString("aab"))
+	cfg.Set("values", "badregexp", "a(b")
+	_, err = cfg.Regexp("values", "badregexp")
+	assert.Err(t, err)
+	defre := regexp.MustCompile("x")
+	assert.Equal(t, cfg.RegexpDefault("values", "badregexp", defre), defre)
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// includeDirective includes other files into a configuration.
+const includeDirective = "!include"
+
+var errIncludeCycle = errors.New("file includes itself")
+
+// isInclude checks, if the passed, trimmed line is an include directive.
+func isInclude(line string) bool {
+	if !strings.HasPrefix(line, includeDirective) {
+		return false
+	}
+	rest := line[len(includeDirective):]
+	return rest == "" || rest[0] == ' ' || rest[0] == '\t'
+}
+
+// LoadDropIn loads the configuration from the passed file and merges all
+// files with the extension .ini of the drop-in directory dir into it. The
+// drop-in files are read in lexical order, values of later files replace
+// those of earlier ones. If dir does not exist, only the file is loaded.
+//
+// The drop-in files are treated like included files and thus are not written
+// by WriteTo or SaveFile.
+func LoadDropIn(filename, dir string, validate Validator) (*Config, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	cfg := newConfig()
+	p := &parser{cfg: cfg, file: filename, dir: filepath.Dir(filename)}
+	if err := p.parse(file); err != nil {
+		return nil, err
+	}
+	pattern := filepath.Join(dir, "*.ini")
+	cfg.patterns = append(cfg.patterns, pattern)
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	for _, fname := range files {
+		if err := p.includeFile(fname); err != nil {
+			return nil, err
+		}
+	}
+	if validate != nil {
+		return cfg, validate(cfg)
+	}
+	return cfg, nil
+}
+
+// include processes the include directive in the passed line. The pattern
+// may contain wildcards as understood by filepath.Match. Matching files are
+// included in lexical order, a pattern without any match is not an error.
+func (p *parser) include(offset, col int, pattern string) error {
+	if pattern == "" {
+		return p.errorf(offset, col, "include directive misses a file")
+	}
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(p.dir, pattern)
+	}
+	files := []string{pattern}
+	if strings.ContainsAny(pattern, "*?[") {
+		var err error
+		if files, err = filepath.Glob(pattern); err != nil {
+			return p.errorf(offset, col, "invalid include pattern '%s': %v", pattern, err)
+		}
+		p.cfg.patterns = append(p.cfg.patterns, pattern)
+	}
+	for _, fname := range files {
+		if err := p.includeFile(fname); err != nil {
+			if _, ok := err.(*os.PathError); ok {
+				return p.errorf(offset, col, "%w", err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// includeFile parses the passed file into the Config of the parser.
+func (p *parser) includeFile(fname string) error {
+	abs, err := filepath.Abs(fname)
+	if err != nil {
+		return err
+	}
+	parents := p.parents
+	if p.file != "" {
+		cur, err := filepath.Abs(p.file)
+		if err != nil {
+			return err
+		}
+		parents = append(parents[:len(parents):len(parents)], cur)
+	}
+	for _, parent := range parents {
+		if parent == abs {
+			return &os.PathError{Op: "include", Path: fname, Err: errIncludeCycle}
+		}
+	}
+	file, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	sub := &parser{
+		cfg:      p.cfg,
+		file:     fname,
+		dir:      filepath.Dir(fname),
+		included: true,
+		parents:  parents,
+	}
+	return sub.parse(file)
+}
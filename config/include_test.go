@@ -0,0 +1,178 @@
+package config_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/marcusva/gadget/config"
+	"github.com/marcusva/gadget/testing/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		fname := filepath.Join(dir, name)
+		assert.FailOnErr(t, os.MkdirAll(filepath.Dir(fname), 0755))
+		assert.FailOnErr(t, ioutil.WriteFile(fname, []byte(content), 0644))
+	}
+}
+
+func TestInclude(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "gadget-cfgtest")
+	assert.FailOnErr(t, err)
+	defer os.RemoveAll(dir)
+
+	main := `[log]
+level = Debug
+!include sub/*.ini
+
+[server]
+port = 80
+`
+	writeFiles(t, dir, map[string]string{
+		"main.ini":         main,
+		"sub/a.ini":        "[log]\nlevel = Info\nfile = a.log\n",
+		"sub/b.ini":        "[log]\nfile = b.log\n[db]\nhost = localhost\n",
+		"sub/ignored.conf": "[ignored]\nkey = value\n",
+	})
+
+	cfg, err := config.LoadFile(filepath.Join(dir, "main.ini"), nil)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, cfg.GetOrPanic("log", "level"), "Info")
+	assert.Equal(t, cfg.GetOrPanic("log", "file"), "b.log")
+	assert.Equal(t, cfg.GetOrPanic("db", "host"), "localhost")
+	assert.Equal(t, cfg.GetOrPanic("server", "port"), "80")
+	assert.FailIf(t, cfg.HasSection("ignored"))
+
+	// Included lines are not written.
+	var buf bytes.Buffer
+	_, err = cfg.WriteTo(&buf)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, buf.String(), main)
+
+	cfg.Set("server", "port", "8080")
+	cfg.Set("db", "user", "admin")
+	buf.Reset()
+	_, err = cfg.WriteTo(&buf)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, buf.String(), strings.Replace(main, "80", "8080", 1)+"\n[db]\nuser = admin\n")
+
+	// Keys of included files would be overridden by them on loading
+	cfg.Set("db", "host", "db.example.com")
+	_, err = cfg.WriteTo(ioutil.Discard)
+	assert.Err(t, err)
+	cfg.Set("db", "host", "localhost")
+	cfg.Remove("log", "level")
+	_, err = cfg.WriteTo(ioutil.Discard)
+	assert.Err(t, err)
+}
+
+func TestIncludeErrors(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "gadget-cfgtest")
+	assert.FailOnErr(t, err)
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"missing.ini":    "[log]\n!include nonexisting.ini\n",
+		"nomatch.ini":    "[log]\n!include nonexisting/*.ini\n",
+		"empty.ini":      "!include\n",
+		"cycle.ini":      "!include cycle2.ini\n",
+		"cycle2.ini":     "[log]\nlevel = Info\n!include cycle.ini\n",
+		"broken.ini":     "[log]\n!include sub/broken.ini\n",
+		"sub/broken.ini": "[log]\nlevel Info\n",
+		"twice.ini":      "[log]\nlevel = Debug\n[server]\n[log]\nlevel = Info\nfile = a.log\n",
+	})
+
+	_, err = config.LoadFile(filepath.Join(dir, "missing.ini"), nil)
+	assert.Err(t, err)
+	var perr *config.ParseError
+	assert.FailIfNot(t, errors.As(err, &perr), "invalid error type %T", err)
+	assert.Equal(t, perr.Line, 2)
+	assert.FailIfNot(t, errors.Is(err, os.ErrNotExist), "invalid error %v", err)
+
+	_, err = config.LoadFile(filepath.Join(dir, "nomatch.ini"), nil)
+	assert.FailOnErr(t, err)
+
+	_, err = config.LoadFile(filepath.Join(dir, "empty.ini"), nil)
+	assert.Err(t, err)
+
+	_, err = config.LoadFile(filepath.Join(dir, "cycle.ini"), nil)
+	assert.Err(t, err)
+
+	_, err = config.LoadFile(filepath.Join(dir, "broken.ini"), nil)
+	assert.Err(t, err)
+	assert.Equal(t, err.Error(), filepath.Join(dir, "sub", "broken.ini")+
+		": line 2, column 1: key-value definition misses assignment")
+
+	// Sections declared twice are merged
+	cfg, err := config.LoadFile(filepath.Join(dir, "twice.ini"), nil)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, cfg.Sections["log"], map[string]string{"level": "Info", "file": "a.log"})
+}
+
+func TestLoadDropIn(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "gadget-cfgtest")
+	assert.FailOnErr(t, err)
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"main.ini":        "[log]\nlevel = Debug\nfile = main.log\n",
+		"conf.d/20-b.ini": "[log]\nlevel = Error\n",
+		"conf.d/10-a.ini": "[log]\nlevel = Info\n[extra]\nkey = value\n",
+	})
+
+	fname := filepath.Join(dir, "main.ini")
+	cfg, err := config.LoadDropIn(fname, filepath.Join(dir, "conf.d"), nil)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, cfg.GetOrPanic("log", "level"), "Error")
+	assert.Equal(t, cfg.GetOrPanic("log", "file"), "main.log")
+	assert.Equal(t, cfg.GetOrPanic("extra", "key"), "value")
+
+	cfg, err = config.LoadDropIn(fname, filepath.Join(dir, "missing"), nil)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, cfg.GetOrPanic("log", "level"), "Debug")
+
+	_, err = config.LoadDropIn(filepath.Join(dir, "missing.ini"), filepath.Join(dir, "conf.d"), nil)
+	assert.Err(t, err)
+}
+
+func TestIncludeErrorFile(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "gadget-cfgtest")
+	assert.FailOnErr(t, err)
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"test.ini": "[server]\nhost = localhost\n!include sub.ini\n",
+		"sub.ini":  "[server]\nport = abc\n",
+	})
+	sub := filepath.Join(dir, "sub.ini")
+	cfg, err := config.LoadFile(filepath.Join(dir, "test.ini"), nil)
+	assert.FailOnErr(t, err)
+
+	var s struct {
+		Port int `ini:"server.port"`
+	}
+	err = cfg.Unmarshal(&s)
+	var derr *config.DecodeError
+	assert.FailIfNot(t, errors.As(err, &derr), "invalid error type %T", err)
+	assert.Equal(t, derr.File, sub)
+	assert.Equal(t, derr.Line, 2)
+	assert.FailIfNot(t, strings.HasPrefix(err.Error(), sub+": line 2: section 'server', key 'port': "),
+		"unexpected error: %v", err)
+
+	schema := config.Schema{
+		Sections: map[string]config.Section{
+			"server": {Keys: map[string]config.Key{"port": {Type: config.TypeInt}}},
+		},
+	}
+	err = schema.Validator()(cfg)
+	var v *config.Violation
+	assert.FailIfNot(t, errors.As(err, &v), "invalid error type %T", err)
+	assert.Equal(t, v.File, sub)
+	assert.Equal(t, v.Line, 2)
+	assert.FailIfNot(t, strings.HasPrefix(err.Error(), sub+": line 2: section 'server', key 'port': "),
+		"unexpected error: %v", err)
+}
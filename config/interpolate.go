@@ -0,0 +1,165 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ExpandError is returned by Expand and Interpolate, if a reference of a
+// value cannot be resolved.
+type ExpandError struct {
+	// Position is the position of the referencing key. Its line is 0, if the
+	// key was not loaded, e.g. because it was added via Set.
+	Position
+	// Section is the section of the referencing key.
+	Section string
+	// Key is the referencing key.
+	Key string
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *ExpandError) Error() string {
+	msg := fmt.Sprintf("section '%s', key '%s': %v", e.Section, e.Key, e.Err)
+	if e.Line > 0 {
+		msg = fmt.Sprintf("line %d: %s", e.Line, msg)
+		if e.File != "" {
+			msg = e.File + ": " + msg
+		}
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error.
+func (e *ExpandError) Unwrap() error {
+	return e.Err
+}
+
+// Expand gets the value for a certain key within the specified section and
+// resolves all references to other values in it. References have the
+// following forms:
+//
+//	${key}          the value of key within the same section
+//	${section:key}  the value of key within section
+//	${env:NAME}     the value of the environment variable NAME
+//
+// Referenced values are expanded recursively. A literal "$" can be written as
+// "$$". Cyclic references, unknown keys and unset environment variables cause
+// an *ExpandError, which contains the position of the referencing value.
+func (cfg *Config) Expand(section, key string) (string, error) {
+	return cfg.expand(section, key, make(map[sectionKey]bool))
+}
+
+// Interpolate resolves the references of all values as described for Expand
+// and replaces the values of the Config with the results. Since "$$" is
+// replaced by "$", Interpolate must be called only once for a Config.
+func (cfg *Config) Interpolate() error {
+	expanded := make(map[string]map[string]string, len(cfg.Sections))
+	for section, values := range cfg.Sections {
+		expanded[section] = make(map[string]string, len(values))
+		for key := range values {
+			value, err := cfg.Expand(section, key)
+			if err != nil {
+				return err
+			}
+			expanded[section][key] = value
+		}
+	}
+	multi := make(map[sectionKey][]string, len(cfg.multi))
+	for sk, values := range cfg.multi {
+		multi[sk] = make([]string, len(values))
+		for idx, value := range values {
+			var err error
+			visiting := map[sectionKey]bool{sk: true}
+			if multi[sk][idx], err = cfg.expandValue(sk.section, sk.key, value, visiting); err != nil {
+				return err
+			}
+		}
+	}
+	for section, values := range expanded {
+		for key, value := range values {
+			cfg.Sections[section][key] = value
+		}
+	}
+	for sk, values := range multi {
+		cfg.multi[sk] = values
+	}
+	return nil
+}
+
+func (cfg *Config) expand(section, key string, visiting map[sectionKey]bool) (string, error) {
+	section, key = cfg.foldSection(section), cfg.fold(key)
+	value, err := cfg.Get(section, key)
+	if err != nil {
+		return "", err
+	}
+	sk := sectionKey{section, key}
+	if visiting[sk] {
+		return "", cfg.expandError(section, key, errors.New("cyclic reference"))
+	}
+	visiting[sk] = true
+	defer delete(visiting, sk)
+	return cfg.expandValue(section, key, value, visiting)
+}
+
+// expandValue resolves the references of a value of the passed key.
+func (cfg *Config) expandValue(section, key, value string, visiting map[sectionKey]bool) (string, error) {
+	var buf strings.Builder
+	for {
+		idx := strings.IndexByte(value, '$')
+		if idx == -1 || idx == len(value)-1 {
+			buf.WriteString(value)
+			break
+		}
+		buf.WriteString(value[:idx])
+		switch value[idx+1] {
+		case '$':
+			buf.WriteByte('$')
+			value = value[idx+2:]
+			continue
+		case '{':
+		default:
+			buf.WriteByte('$')
+			value = value[idx+1:]
+			continue
+		}
+		end := strings.IndexByte(value[idx:], '}')
+		if end == -1 {
+			return "", cfg.expandError(section, key, errors.New("unterminated reference"))
+		}
+		ref := value[idx+2 : idx+end]
+		value = value[idx+end+1:]
+
+		refsection, refkey := section, ref
+		if sep := strings.LastIndexByte(ref, ':'); sep != -1 {
+			refsection, refkey = ref[:sep], ref[sep+1:]
+		}
+		if refsection == "env" {
+			env, ok := os.LookupEnv(refkey)
+			if !ok {
+				return "", cfg.expandError(section, key,
+					fmt.Errorf("environment variable '%s' is not set", refkey))
+			}
+			buf.WriteString(env)
+			continue
+		}
+		if _, err := cfg.Get(refsection, refkey); err != nil {
+			return "", cfg.expandError(section, key, fmt.Errorf("invalid reference '${%s}': %w", ref, err))
+		}
+		resolved, err := cfg.expand(refsection, refkey, visiting)
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString(resolved)
+	}
+	return buf.String(), nil
+}
+
+// expandError creates an ExpandError for an invalid value, including its
+// position, if known.
+func (cfg *Config) expandError(section, key string, err error) error {
+	pos, _ := cfg.KeyPosition(section, key)
+	return &ExpandError{Position: pos, Section: section, Key: key, Err: err}
+}
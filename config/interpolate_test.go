@@ -0,0 +1,121 @@
+package config_test
+
+import (
+	"errors"
+	"github.com/marcusva/gadget/config"
+	"github.com/marcusva/gadget/testing/assert"
+	"os"
+	"strings"
+	"testing"
+)
+
+var _interpolatecfg = `
+[paths]
+base = /opt/app
+data = ${base}/data
+cache = ${data}/cache
+home = ${env:GADGETTEST_HOME}/.app
+
+[server]
+root = ${paths:data}/www
+price = $$5 or $10
+partial = ${broken
+
+[cycle]
+a = ${b}
+b = ${cycle:a}
+
+[invalid]
+ref = ${unknown}
+env = ${env:GADGETTEST_UNSET}
+`
+
+func TestExpand(t *testing.T) {
+	defer setenv(t, map[string]string{"GADGETTEST_HOME": "/home/user"})()
+	os.Unsetenv("GADGETTEST_UNSET")
+	cfg, err := config.Load(strings.NewReader(_interpolatecfg), config.NoValidate)
+	assert.FailOnErr(t, err)
+
+	expected := map[string]string{
+		"base":  "/opt/app",
+		"data":  "/opt/app/data",
+		"cache": "/opt/app/data/cache",
+		"home":  "/home/user/.app",
+	}
+	for key, value := range expected {
+		result, err := cfg.Expand("paths", key)
+		assert.FailOnErr(t, err)
+		assert.Equal(t, result, value)
+	}
+	result, err := cfg.Expand("server", "root")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, result, "/opt/app/data/www")
+	result, err = cfg.Expand("server", "price")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, result, "$5 or $10")
+
+	// The raw values are kept
+	assert.Equal(t, cfg.GetDefault("paths", "data", ""), "${base}/data")
+
+	_, err = cfg.Expand("server", "partial")
+	assert.Equal(t, err.Error(), "line 11: section 'server', key 'partial': unterminated reference")
+	_, err = cfg.Expand("cycle", "a")
+	assert.Equal(t, err.Error(), "line 14: section 'cycle', key 'a': cyclic reference")
+	_, err = cfg.Expand("invalid", "ref")
+	assert.FailIfNot(t, strings.HasPrefix(err.Error(), "line 18: section 'invalid', key 'ref': invalid reference '${unknown}'"),
+		"unexpected error: %v", err)
+	_, err = cfg.Expand("invalid", "env")
+	assert.Equal(t, err.Error(), "line 19: section 'invalid', key 'env': environment variable 'GADGETTEST_UNSET' is not set")
+	_, err = cfg.Expand("invalid", "missing")
+	assert.Err(t, err)
+}
+
+func TestExpandError(t *testing.T) {
+	cfg, err := config.Load(strings.NewReader(_interpolatecfg), nil)
+	assert.FailOnErr(t, err)
+
+	_, err = cfg.Expand("invalid", "ref")
+	var experr *config.ExpandError
+	assert.FailIfNot(t, errors.As(err, &experr), "unexpected error type %T", err)
+	assert.Equal(t, experr.Section, "invalid")
+	assert.Equal(t, experr.Key, "ref")
+	assert.Equal(t, experr.Line, 18)
+	assert.FailIfNot(t, errors.Is(err, config.ErrKeyNotFound), "unexpected error: %v", err)
+
+	cfg.Set("invalid", "set", "${env:GADGETTEST_UNSET}")
+	_, err = cfg.Expand("invalid", "set")
+	assert.FailIfNot(t, errors.As(err, &experr), "unexpected error type %T", err)
+	assert.Equal(t, experr.Position, config.Position{})
+	assert.Equal(t, err.Error(), "section 'invalid', key 'set': environment variable 'GADGETTEST_UNSET' is not set")
+}
+
+func TestInterpolate(t *testing.T) {
+	defer setenv(t, map[string]string{"GADGETTEST_HOME": "/home/user"})()
+	cfg, err := config.Load(strings.NewReader(_interpolatecfg), config.NoValidate)
+	assert.FailOnErr(t, err)
+	assert.Err(t, cfg.Interpolate())
+	// Nothing is replaced on errors
+	assert.Equal(t, cfg.GetDefault("paths", "data", ""), "${base}/data")
+
+	cfg.RemoveSection("cycle")
+	cfg.RemoveSection("invalid")
+	cfg.Remove("server", "partial")
+	assert.FailOnErr(t, cfg.Interpolate())
+	assert.Equal(t, cfg.GetDefault("paths", "data", ""), "/opt/app/data")
+	assert.Equal(t, cfg.GetDefault("server", "price", ""), "$5 or $10")
+}
+
+func TestInterpolateList(t *testing.T) {
+	defer setenv(t, map[string]string{"GADGETTEST_HOME": "/home/user"})()
+
+	cfg, err := config.Load(strings.NewReader("[a]\nbase = /opt\np[] = ${env:GADGETTEST_HOME}/1\np[] = ${base}/2\n"), nil)
+	assert.FailOnErr(t, err)
+	assert.FailOnErr(t, cfg.Interpolate())
+	values, err := cfg.List("a", "p")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, values, []string{"/home/user/1", "/opt/2"})
+
+	cfg, err = config.Load(strings.NewReader("[a]\np[] = ${p}\np[] = x\n"), nil)
+	assert.FailOnErr(t, err)
+	assert.Err(t, cfg.Interpolate())
+}
@@ -0,0 +1,302 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// splitList splits a comma-separated value into its elements, keeping
+// quoted elements, which may contain commas, as they are. Quotes only start
+// a quoted part at the beginning of an element or of a map value after the
+// ':', e.g. O'Brien is a plain element.
+func splitList(val string) ([]string, error) {
+	var elems []string
+	start := 0
+	for i := 0; i < len(val); i++ {
+		switch val[i] {
+		case '"', '\'':
+			if prefix := strings.TrimSpace(val[start:i]); prefix != "" && !strings.HasSuffix(prefix, ":") {
+				continue
+			}
+			end := closingQuote(val[i:])
+			if end == -1 {
+				return nil, errors.New("list element misses the closing quote")
+			}
+			i += end
+		case ',':
+			elems = append(elems, strings.TrimSpace(val[start:i]))
+			start = i + 1
+		}
+	}
+	return append(elems, strings.TrimSpace(val[start:])), nil
+}
+
+// unquoteElement removes the quotes around a list or map element. Double
+// quoted elements may contain the escape sequences of Go string literals,
+// single quoted elements are taken verbatim. Elements, which are not
+// quoted as a whole, are returned as they are.
+func unquoteElement(elem string) (string, error) {
+	if len(elem) < 2 || (elem[0] != '"' && elem[0] != '\'') || closingQuote(elem) != len(elem)-1 {
+		return elem, nil
+	}
+	if elem[0] == '\'' {
+		return elem[1 : len(elem)-1], nil
+	}
+	value, err := strconv.Unquote(elem)
+	if err != nil {
+		return "", fmt.Errorf("invalid escape sequence in element %s", elem)
+	}
+	return value, nil
+}
+
+// listElements splits a comma-separated value into its unquoted elements. A
+// blank value has no elements.
+func listElements(val string) ([]string, error) {
+	elems, err := splitList(val)
+	if err != nil {
+		return nil, err
+	}
+	if len(elems) == 1 && elems[0] == "" {
+		return []string{}, nil
+	}
+	for idx, elem := range elems {
+		if elems[idx], err = unquoteElement(elem); err != nil {
+			return nil, err
+		}
+	}
+	return elems, nil
+}
+
+// mapElements splits a comma-separated value into its unquoted key:value
+// pairs. A blank value has no pairs.
+func mapElements(val string) (map[string]string, error) {
+	elems, err := splitList(val)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string, len(elems))
+	for _, elem := range elems {
+		if elem == "" && len(elems) == 1 {
+			break
+		}
+		k, v, err := splitPair(elem)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := result[k]; ok {
+			return nil, fmt.Errorf("duplicate map key '%s'", k)
+		}
+		result[k] = v
+	}
+	return result, nil
+}
+
+// List gets the values of a certain key within the specified section as
+// list. Keys declared multiple times via key[] or with the
+// DuplicateAccumulate policy provide one element per definition:
+//
+//	server[] = a.example.com
+//	server[] = b.example.com
+//
+// All other values are split at commas. Elements can be quoted to contain
+// commas, the quotes are removed. A blank value results in an empty list.
+//
+//	server = a.example.com, "b.example.com, backup", 'c'
+func (cfg *Config) List(section, key string) ([]string, error) {
+	values, err := cfg.Values(section, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) > 1 || cfg.lists[sectionKey{cfg.foldSection(section), cfg.fold(key)}] {
+		return values, nil
+	}
+	var result []string
+	err = cfg.convert(section, key, func(val string) (err error) {
+		result, err = listElements(val)
+		return err
+	})
+	return result, err
+}
+
+// Map gets a value for a certain key within the specified section as map.
+// The value consists of comma-separated key:value pairs, of which the keys
+// and values may be quoted as with List:
+//
+//	weights = a:1, b:2, "c:d": 3
+func (cfg *Config) Map(section, key string) (map[string]string, error) {
+	var result map[string]string
+	err := cfg.convert(section, key, func(val string) (err error) {
+		result, err = mapElements(val)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// splitPair splits a key:value element of a map value.
+func splitPair(elem string) (string, string, error) {
+	sep := -1
+	for i := 0; i < len(elem) && sep == -1; i++ {
+		switch elem[i] {
+		case '"', '\'':
+			// Only a quoted key may contain a ':'.
+			if strings.TrimSpace(elem[:i]) == "" {
+				i += closingQuote(elem[i:])
+			}
+		case ':':
+			sep = i
+		}
+	}
+	if sep == -1 {
+		return "", "", fmt.Errorf("map element '%s' misses a ':'", elem)
+	}
+	k, err := unquoteElement(strings.TrimSpace(elem[:sep]))
+	if err != nil {
+		return "", "", err
+	}
+	v, err := unquoteElement(strings.TrimSpace(elem[sep+1:]))
+	if err != nil {
+		return "", "", err
+	}
+	return k, v, nil
+}
+
+// convertList gets the elements of a list via List and passes each of them
+// to fn. Errors of fn are returned as ValueError for the element.
+func (cfg *Config) convertList(section, key string, fn func(idx int, elem string) error) (int, error) {
+	elems, err := cfg.List(section, key)
+	if err != nil {
+		return 0, err
+	}
+	for idx, elem := range elems {
+		if err := fn(idx, elem); err != nil {
+			if ne, ok := err.(*strconv.NumError); ok {
+				err = ne.Err
+			}
+			return 0, &ValueError{Section: section, Key: key, Value: elem, Err: err}
+		}
+	}
+	return len(elems), nil
+}
+
+// convertMap gets the pairs of a map via Map and passes each of them to fn.
+// Errors of fn are returned as ValueError for the value of the pair.
+func (cfg *Config) convertMap(section, key string, fn func(k, v string) error) error {
+	pairs, err := cfg.Map(section, key)
+	if err != nil {
+		return err
+	}
+	for k, v := range pairs {
+		if err := fn(k, v); err != nil {
+			if ne, ok := err.(*strconv.NumError); ok {
+				err = ne.Err
+			}
+			return &ValueError{Section: section, Key: key, Value: v, Err: fmt.Errorf("map key '%s': %v", k, err)}
+		}
+	}
+	return nil
+}
+
+// Int64List gets the elements of a list as described for List as int64
+// values.
+func (cfg *Config) Int64List(section, key string) ([]int64, error) {
+	var result []int64
+	_, err := cfg.convertList(section, key, func(idx int, elem string) error {
+		v, err := strconv.ParseInt(elem, 10, 64)
+		result = append(result, v)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Float64List gets the elements of a list as described for List as float64
+// values.
+func (cfg *Config) Float64List(section, key string) ([]float64, error) {
+	var result []float64
+	_, err := cfg.convertList(section, key, func(idx int, elem string) error {
+		v, err := strconv.ParseFloat(elem, 64)
+		result = append(result, v)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// BoolList gets the elements of a list as described for List as bool
+// values.
+func (cfg *Config) BoolList(section, key string) ([]bool, error) {
+	var result []bool
+	_, err := cfg.convertList(section, key, func(idx int, elem string) error {
+		v, err := strconv.ParseBool(elem)
+		result = append(result, v)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DurationList gets the elements of a list as described for List as
+// time.Duration values.
+func (cfg *Config) DurationList(section, key string) ([]time.Duration, error) {
+	var result []time.Duration
+	_, err := cfg.convertList(section, key, func(idx int, elem string) error {
+		v, err := time.ParseDuration(elem)
+		result = append(result, v)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Int64Map gets a map as described for Map with int64 values.
+func (cfg *Config) Int64Map(section, key string) (map[string]int64, error) {
+	result := make(map[string]int64)
+	err := cfg.convertMap(section, key, func(k, v string) (err error) {
+		result[k], err = strconv.ParseInt(v, 10, 64)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Float64Map gets a map as described for Map with float64 values.
+func (cfg *Config) Float64Map(section, key string) (map[string]float64, error) {
+	result := make(map[string]float64)
+	err := cfg.convertMap(section, key, func(k, v string) (err error) {
+		result[k], err = strconv.ParseFloat(v, 64)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DurationMap gets a map as described for Map with time.Duration values.
+func (cfg *Config) DurationMap(section, key string) (map[string]time.Duration, error) {
+	result := make(map[string]time.Duration)
+	err := cfg.convertMap(section, key, func(k, v string) (err error) {
+		result[k], err = time.ParseDuration(v)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
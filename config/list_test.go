@@ -0,0 +1,210 @@
+package config_test
+
+import (
+	"bytes"
+	"github.com/marcusva/gadget/config"
+	"github.com/marcusva/gadget/testing/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+const _lists = `[values]
+server[] = a.example.com
+server[] = b.example.com
+plain = a, b ,c
+quoted = a, "b, c", 'd "e"', "f\tg"
+single = one
+apostrophes = O'Brien, don't, "it's, quoted"
+empty =
+unterminated = a, "b
+ints = 1, 2, 3
+badints = 1, x
+floats[] = 1.5
+floats[] = 2
+bools = true, false
+durations = 1s, 2m
+weights = a:1, b: 2, "c:d": 3
+names = 'x y': "1, 2"
+nomap = a, b
+dupmap = a:1, a:2
+timeouts = read:1s, write:2m
+`
+
+func loadLists(t *testing.T) *config.Config {
+	cfg, err := config.Load(strings.NewReader(_lists), nil)
+	assert.FailOnErr(t, err)
+	return cfg
+}
+
+func TestList(t *testing.T) {
+	cfg := loadLists(t)
+
+	values, err := cfg.List("values", "server")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, values, []string{"a.example.com", "b.example.com"})
+	assert.Equal(t, cfg.GetOrPanic("values", "server"), "b.example.com")
+
+	values, err = cfg.List("values", "plain")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, values, []string{"a", "b", "c"})
+
+	values, err = cfg.List("values", "quoted")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, values, []string{"a", "b, c", "d \"e\"", "f\tg"})
+
+	values, err = cfg.List("values", "single")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, values, []string{"one"})
+
+	values, err = cfg.List("values", "apostrophes")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, values, []string{"O'Brien", "don't", "it's, quoted"})
+
+	values, err = cfg.List("values", "empty")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, len(values), 0)
+
+	_, err = cfg.List("values", "unterminated")
+	assert.Err(t, err)
+	_, ok := err.(*config.ValueError)
+	assert.FailIfNot(t, ok, "invalid error type %T", err)
+
+	_, err = cfg.List("values", "missing")
+	assert.Err(t, err)
+}
+
+func TestListRepeated(t *testing.T) {
+	// A single key[] definition is a list with a single element.
+	cfg, err := config.Load(strings.NewReader("[a]\nkey[] = x, y\n"), nil)
+	assert.FailOnErr(t, err)
+	values, err := cfg.List("a", "key")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, values, []string{"x, y"})
+
+	// Repeated keys with the DuplicateAccumulate policy
+	opts := config.Options{Duplicates: config.DuplicateAccumulate}
+	cfg, err = config.LoadOptions(strings.NewReader("[a]\nkey = x\nkey = y\n"), opts, nil)
+	assert.FailOnErr(t, err)
+	values, err = cfg.List("a", "key")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, values, []string{"x", "y"})
+
+	// Plain keys followed by key[] keep the plain value
+	cfg, err = config.Load(strings.NewReader("[a]\nkey = x\nkey[] = y\n"), nil)
+	assert.FailOnErr(t, err)
+	values, err = cfg.List("a", "key")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, values, []string{"x", "y"})
+
+	// key[] is not affected by the DuplicateError policy
+	opts = config.Options{Duplicates: config.DuplicateError}
+	cfg, err = config.LoadOptions(strings.NewReader("[a]\nkey[] = x\nkey[] = y\n"), opts, nil)
+	assert.FailOnErr(t, err)
+	values, err = cfg.Values("a", "key")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, values, []string{"x", "y"})
+}
+
+func TestTypedLists(t *testing.T) {
+	cfg := loadLists(t)
+
+	ints, err := cfg.Int64List("values", "ints")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, ints, []int64{1, 2, 3})
+
+	_, err = cfg.Int64List("values", "badints")
+	assert.Err(t, err)
+	verr, ok := err.(*config.ValueError)
+	assert.FailIfNot(t, ok, "invalid error type %T", err)
+	assert.Equal(t, verr.Value, "x")
+
+	floats, err := cfg.Float64List("values", "floats")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, floats, []float64{1.5, 2})
+
+	bools, err := cfg.BoolList("values", "bools")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, bools, []bool{true, false})
+
+	durations, err := cfg.DurationList("values", "durations")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, durations, []time.Duration{time.Second, 2 * time.Minute})
+}
+
+func TestMap(t *testing.T) {
+	cfg := loadLists(t)
+
+	m, err := cfg.Map("values", "weights")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, m, map[string]string{"a": "1", "b": "2", "c:d": "3"})
+
+	m, err = cfg.Map("values", "names")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, m, map[string]string{"x y": "1, 2"})
+
+	m, err = cfg.Map("values", "empty")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, len(m), 0)
+
+	_, err = cfg.Map("values", "nomap")
+	assert.Err(t, err)
+	_, ok := err.(*config.ValueError)
+	assert.FailIfNot(t, ok, "invalid error type %T", err)
+
+	_, err = cfg.Map("values", "dupmap")
+	assert.Err(t, err)
+
+	ints, err := cfg.Int64Map("values", "weights")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, ints, map[string]int64{"a": 1, "b": 2, "c:d": 3})
+
+	floats, err := cfg.Float64Map("values", "weights")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, floats, map[string]float64{"a": 1, "b": 2, "c:d": 3})
+
+	durations, err := cfg.DurationMap("values", "timeouts")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, durations, map[string]time.Duration{"read": time.Second, "write": 2 * time.Minute})
+
+	_, err = cfg.Int64Map("values", "timeouts")
+	assert.Err(t, err)
+	_, ok = err.(*config.ValueError)
+	assert.FailIfNot(t, ok, "invalid error type %T", err)
+}
+
+func TestUnmarshalRepeated(t *testing.T) {
+	cfg := loadLists(t)
+	var settings struct {
+		Servers []string  `ini:"values.server"`
+		Floats  []float64 `ini:"values.floats"`
+		Plain   []string  `ini:"values.plain"`
+	}
+	assert.FailOnErr(t, cfg.Unmarshal(&settings))
+	assert.Equal(t, settings.Servers, []string{"a.example.com", "b.example.com"})
+	assert.Equal(t, settings.Floats, []float64{1.5, 2})
+	assert.Equal(t, settings.Plain, []string{"a", "b", "c"})
+}
+
+func TestWriteRepeated(t *testing.T) {
+	const input = "[a]\nkey[] = x\nkey[] = y\nother = z\n"
+	cfg, err := config.Load(strings.NewReader(input), nil)
+	assert.FailOnErr(t, err)
+
+	var buf bytes.Buffer
+	_, err = cfg.WriteTo(&buf)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, buf.String(), input)
+
+	cfg.Set("a", "key", "w")
+	buf.Reset()
+	_, err = cfg.WriteTo(&buf)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, buf.String(), "[a]\nkey[] = w\nother = z\n")
+
+	cfg, err = config.Load(strings.NewReader(buf.String()), nil)
+	assert.FailOnErr(t, err)
+	values, err := cfg.List("a", "key")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, values, []string{"w"})
+}
@@ -0,0 +1,309 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Options enables extended syntax features on loading a configuration. By
+// default, all features are disabled and everything after the '=' is taken
+// verbatim as value, with the surrounding whitespace removed.
+//
+//	[section]
+//	# Quotes
+//	quoted = "  leading spaces, a \"quote\" and a newline\n"
+//	single = 'no \escapes'
+//	# InlineComments
+//	key = value  # a comment
+//	# Continuation
+//	long = a value spanning \
+//	       two lines
+//	# IndentContinuation
+//	lines = first line
+//	    second line
+//	# Heredoc
+//	text = <<END
+//	everything up to the end marker
+//	  is taken verbatim
+//	END
+type Options struct {
+	// Quotes enables values enclosed in double or single quotes. Double
+	// quoted values may contain the escape sequences of Go string literals,
+	// such as \n, \t, \" or \u00e4. Single quoted values are taken verbatim.
+	Quotes bool
+	// InlineComments enables comments after a value. A comment starts with a
+	// '#' or ';' preceded by whitespace. Quoted values may contain those
+	// characters.
+	InlineComments bool
+	// Continuation enables continuation lines: if a value ends with a
+	// backslash, the next line, with its surrounding whitespace removed, is
+	// appended to it.
+	Continuation bool
+	// IndentContinuation enables indentation-based continuation lines: all
+	// indented lines following a key up to the next empty line are appended
+	// to its value separated by a newline. Indented comments are skipped.
+	// Keys must not be indented in this mode.
+	IndentContinuation bool
+	// Heredoc enables multi-line values in the form "key = <<MARKER". All
+	// lines up to the line only consisting of MARKER are taken verbatim as
+	// value.
+	Heredoc bool
+
+	// GlobalSection is the name of the section for the keys before the first
+	// section header. If it is empty, such keys are rejected.
+	GlobalSection string
+	// CaseInsensitive enables the case-insensitive matching of section and
+	// key names. The names are converted to lower case on loading and on
+	// accessing the Config.
+	CaseInsensitive bool
+	// Duplicates defines, how keys defined multiple times within the same
+	// section of a file are treated. Keys of included files always replace
+	// the values of the including file.
+	Duplicates DuplicatePolicy
+}
+
+// DuplicatePolicy defines, how duplicate keys are treated on loading a
+// configuration.
+type DuplicatePolicy int
+
+const (
+	// DuplicateLast uses the value of the last definition of a key.
+	DuplicateLast DuplicatePolicy = iota
+	// DuplicateFirst uses the value of the first definition of a key and
+	// ignores all further definitions.
+	DuplicateFirst
+	// DuplicateError rejects duplicate keys.
+	DuplicateError
+	// DuplicateAccumulate keeps the values of all definitions of a key,
+	// which can be retrieved via Values or List. Get returns the value of
+	// the last definition. Keys declared as key[] are always accumulated.
+	DuplicateAccumulate
+)
+
+// ExtendedOptions enables all extended syntax features, except for the
+// IndentContinuation.
+var ExtendedOptions = Options{
+	Quotes:         true,
+	InlineComments: true,
+	Continuation:   true,
+	Heredoc:        true,
+}
+
+// LoadOptions loads the configuration from a io.Reader using the passed
+// Options. Relative paths of include directives are resolved against the
+// current working directory.
+func LoadOptions(r io.Reader, opts Options, validate Validator) (*Config, error) {
+	cfg := newConfig()
+	cfg.opts = opts
+	p := &parser{cfg: cfg, dir: "."}
+	if err := p.parse(r); err != nil {
+		return nil, err
+	}
+	if validate != nil {
+		return cfg, validate(cfg)
+	}
+	return cfg, nil
+}
+
+// LoadFileOptions loads the configuration from the passed file using the
+// passed Options. Included files are read with the same Options.
+func LoadFileOptions(filename string, opts Options, validate Validator) (*Config, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	cfg := newConfig()
+	cfg.opts = opts
+	p := &parser{cfg: cfg, file: filename, dir: filepath.Dir(filename)}
+	if err := p.parse(file); err != nil {
+		return nil, err
+	}
+	if validate != nil {
+		return cfg, validate(cfg)
+	}
+	return cfg, nil
+}
+
+// value parses the value of the key-value pair in lines[idx], of which text
+// is the part after the '='. It returns the value, the trailing inline
+// comment including the whitespace before it and the index of the last line
+// belonging to the value.
+func (p *parser) value(text string, lines []string, idx int) (value, tail string, next int, err error) {
+	opts := p.cfg.opts
+	text = strings.TrimSpace(text)
+	next = idx
+
+	if opts.Heredoc && strings.HasPrefix(text, "<<") {
+		marker := strings.TrimSpace(text[2:])
+		if marker == "" {
+			return "", "", idx, errors.New("heredoc misses the end marker")
+		}
+		for next = idx + 1; next < len(lines); next++ {
+			if strings.TrimSpace(lines[next]) == marker {
+				return strings.Join(lines[idx+1:next], "\n"), "", next, nil
+			}
+		}
+		return "", "", idx, fmt.Errorf("heredoc misses the end marker '%s'", marker)
+	}
+
+	for opts.Continuation && strings.HasSuffix(text, "\\") && next+1 < len(lines) {
+		next++
+		text = text[:len(text)-1] + strings.TrimSpace(lines[next])
+	}
+	if value, tail, err = parseValue(text, opts); err != nil {
+		return "", "", idx, err
+	}
+
+	if opts.IndentContinuation {
+		for next+1 < len(lines) {
+			raw := lines[next+1]
+			line := strings.TrimSpace(raw)
+			if line == "" || (raw[0] != ' ' && raw[0] != '\t') {
+				break
+			}
+			next++
+			if line[0] == '#' || line[0] == ';' {
+				continue
+			}
+			cont, _, err := parseValue(line, opts)
+			if err != nil {
+				return "", "", idx, err
+			}
+			value += "\n" + cont
+		}
+	}
+	if next != idx {
+		// Comments can only be kept for single line values.
+		tail = ""
+	}
+	return value, tail, next, nil
+}
+
+// parseValue parses a single value, which may be quoted or followed by an
+// inline comment. It returns the value and the comment including the
+// whitespace before it.
+func parseValue(text string, opts Options) (string, string, error) {
+	if opts.Quotes && len(text) > 0 && (text[0] == '"' || text[0] == '\'') {
+		end := closingQuote(text)
+		if end == -1 {
+			return "", "", errors.New("quoted value misses the closing quote")
+		}
+		value := text[1:end]
+		if text[0] == '"' {
+			var err error
+			if value, err = strconv.Unquote(text[:end+1]); err != nil {
+				return "", "", errors.New("invalid escape sequence in quoted value")
+			}
+		}
+		rest := text[end+1:]
+		trimmed := strings.TrimSpace(rest)
+		if trimmed == "" {
+			return value, "", nil
+		}
+		if opts.InlineComments && (trimmed[0] == '#' || trimmed[0] == ';') {
+			return value, rest, nil
+		}
+		return "", "", errors.New("unexpected text after quoted value")
+	}
+	if opts.InlineComments {
+		if pos := commentStart(text); pos != -1 {
+			value := strings.TrimSpace(text[:pos])
+			return value, text[len(strings.TrimRight(text[:pos], " \t")):], nil
+		}
+	}
+	return strings.TrimSpace(text), "", nil
+}
+
+// closingQuote gets the position of the quote closing the quoted text or -1.
+func closingQuote(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && quote == '"':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// commentStart gets the position of an inline comment or -1.
+func commentStart(text string) int {
+	for i := 1; i < len(text); i++ {
+		if (text[i] == '#' || text[i] == ';') && (text[i-1] == ' ' || text[i-1] == '\t') {
+			return i
+		}
+	}
+	return -1
+}
+
+// formatValue formats a value for writing it, so that it is read back as is
+// with the Options of the Config. Values, which would be altered on reading
+// them, are quoted with the Quotes option or written as heredoc with the
+// Heredoc option. Otherwise, such values cause an error.
+func (cfg *Config) formatValue(value string) (string, error) {
+	opts := cfg.opts
+	plain := value == strings.TrimSpace(value) &&
+		!strings.ContainsAny(value, "\n\r") &&
+		!(opts.Quotes && (strings.HasPrefix(value, "\"") || strings.HasPrefix(value, "'"))) &&
+		!(opts.InlineComments && commentStart(value) != -1) &&
+		!(opts.Continuation && strings.HasSuffix(value, "\\")) &&
+		!(opts.Heredoc && strings.HasPrefix(value, "<<"))
+	switch {
+	case plain:
+		return value, nil
+	case opts.Quotes:
+		return strconv.Quote(value), nil
+	case opts.Heredoc && !strings.ContainsRune(value, '\r'):
+		return heredoc(value), nil
+	case strings.ContainsAny(value, "\n\r"):
+		return "", errors.New("value with line breaks requires the Quotes option")
+	case value != strings.TrimSpace(value):
+		return "", errors.New("value with surrounding whitespace requires the Quotes option")
+	case opts.InlineComments && commentStart(value) != -1:
+		return "", errors.New("value with an inline comment requires the Quotes option")
+	default:
+		return "", errors.New("value with a continuation requires the Quotes option")
+	}
+}
+
+// heredoc formats a value as heredoc with an end marker, which is not part of
+// the value.
+func heredoc(value string) string {
+	lines := strings.Split(value, "\n")
+	marker := "END"
+	for idx := 1; ; idx++ {
+		unique := true
+		for _, line := range lines {
+			if strings.TrimSpace(line) == marker {
+				unique = false
+				break
+			}
+		}
+		if unique {
+			break
+		}
+		marker = "END" + strconv.Itoa(idx)
+	}
+	return "<<" + marker + "\n" + value + "\n" + marker
+}
+
+// fold converts a section or key name for the lookup within the Config.
+func (cfg *Config) fold(name string) string {
+	if cfg.opts.CaseInsensitive {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// accumulates checks, if all values of the key are kept.
+func (cfg *Config) accumulates(sk sectionKey) bool {
+	return cfg.opts.Duplicates == DuplicateAccumulate || cfg.lists[sk]
+}
@@ -0,0 +1,260 @@
+package config_test
+
+import (
+	"bytes"
+	"github.com/marcusva/gadget/config"
+	"github.com/marcusva/gadget/testing/assert"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+const _extended = `[values]
+plain = some value
+double = "  a \"quoted\" value\twith escapes\n"
+single = '  no \escapes here '
+hash = "# not a comment" ; but this
+comment = value # a comment
+nocomment = value#still value
+long = a value \
+       spanning lines
+text = <<END
+first line
+  second line
+END
+last = done
+`
+
+func TestLoadOptions(t *testing.T) {
+	cfg, err := config.LoadOptions(strings.NewReader(_extended), config.ExtendedOptions, nil)
+	assert.FailOnErr(t, err)
+
+	expected := map[string]string{
+		"plain":     "some value",
+		"double":    "  a \"quoted\" value\twith escapes\n",
+		"single":    "  no \\escapes here ",
+		"hash":      "# not a comment",
+		"comment":   "value",
+		"nocomment": "value#still value",
+		"long":      "a value spanning lines",
+		"text":      "first line\n  second line",
+		"last":      "done",
+	}
+	for key, value := range expected {
+		assert.Equal(t, cfg.GetOrPanic("values", key), value, key)
+	}
+
+	// Without options, the values are taken verbatim.
+	cfg, err = config.Load(strings.NewReader("[values]\nkey = \"a\" # b\n"), nil)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, cfg.GetOrPanic("values", "key"), "\"a\" # b")
+}
+
+func TestLoadOptionsIndent(t *testing.T) {
+	data := "[values]\nlines = first\n    second\n\t# comment\n\tthird\nkey = value\n"
+	cfg, err := config.LoadOptions(strings.NewReader(data), config.Options{IndentContinuation: true}, nil)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, cfg.GetOrPanic("values", "lines"), "first\nsecond\nthird")
+	assert.Equal(t, cfg.GetOrPanic("values", "key"), "value")
+}
+
+func TestLoadOptionsErrors(t *testing.T) {
+	broken := []string{
+		"[values]\nkey = \"unterminated\n",
+		"[values]\nkey = 'unterminated\n",
+		"[values]\nkey = \"invalid \\q escape\"\n",
+		"[values]\nkey = \"quoted\" trailing\n",
+		"[values]\nkey = <<END\nno end\n",
+		"[values]\nkey = <<\n",
+	}
+	for _, data := range broken {
+		_, err := config.LoadOptions(strings.NewReader(data), config.ExtendedOptions, nil)
+		assert.Err(t, err, data)
+	}
+	_, err := config.LoadOptions(strings.NewReader(broken[0]), config.Options{}, nil)
+	assert.FailOnErr(t, err)
+}
+
+func TestWriteToOptions(t *testing.T) {
+	cfg, err := config.LoadOptions(strings.NewReader(_extended), config.ExtendedOptions, nil)
+	assert.FailOnErr(t, err)
+
+	var buf bytes.Buffer
+	_, err = cfg.WriteTo(&buf)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, buf.String(), _extended)
+
+	cfg.Set("values", "comment", " spaced # value ")
+	cfg.Set("values", "text", "single")
+	cfg.Set("values", "plain", "plain")
+	cfg.Set("values", "new", "multi\nline")
+	buf.Reset()
+	_, err = cfg.WriteTo(&buf)
+	assert.FailOnErr(t, err)
+	written := buf.String()
+	assert.FailIfNot(t, strings.Contains(written, "\ncomment = \" spaced # value \" # a comment\n"), written)
+	assert.FailIfNot(t, strings.Contains(written, "\ntext = single\nlast = done\n"), written)
+	assert.FailIfNot(t, strings.Contains(written, "\nplain = plain\n"), written)
+
+	reloaded, err := config.LoadOptions(strings.NewReader(written), config.ExtendedOptions, nil)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, len(config.Diff(cfg, reloaded)), 0)
+}
+
+func TestWriteToRoundTrip(t *testing.T) {
+	values := []string{
+		"plain",
+		"multi\nline",
+		"trailing newline\n",
+		"  surrounding whitespace ",
+		"value # comment",
+		"value ; comment",
+		"continued \\",
+		"<<END",
+		"END\nmarker",
+		"\"quoted\"",
+		"'single'",
+	}
+	options := []config.Options{
+		{},
+		{InlineComments: true},
+		{Continuation: true},
+		{Heredoc: true},
+		{Heredoc: true, InlineComments: true, Continuation: true},
+		{Quotes: true},
+		config.ExtendedOptions,
+		{Quotes: true, IndentContinuation: true},
+	}
+	for _, opts := range options {
+		for _, value := range values {
+			cfg, err := config.LoadOptions(strings.NewReader("[a]\nkey = old # comment\nnext = 1\n"), opts, nil)
+			assert.FailOnErr(t, err)
+			cfg.Set("a", "key", value)
+			cfg.Set("a", "new", value)
+
+			var buf bytes.Buffer
+			_, err = cfg.WriteTo(&buf)
+			if err != nil {
+				// Only values, which cannot be written as is, are rejected.
+				assert.FailIf(t, opts.Quotes || (opts.Heredoc && !strings.Contains(value, "\r")),
+					"%+v: %q was rejected: %v", opts, value, err)
+				assert.Equal(t, buf.Len(), 0)
+				continue
+			}
+			reloaded, err := config.LoadOptions(strings.NewReader(buf.String()), opts, nil)
+			assert.NoErr(t, err, "%+v: %q", opts, value)
+			if err != nil {
+				continue
+			}
+			assert.Equal(t, reloaded.GetOrPanic("a", "key"), value, "%+v: %q", opts, buf.String())
+			assert.Equal(t, reloaded.GetOrPanic("a", "new"), value, "%+v: %q", opts, buf.String())
+			assert.Equal(t, reloaded.GetOrPanic("a", "next"), "1", "%+v: %q", opts, buf.String())
+		}
+	}
+
+	// Values with line breaks or surrounding whitespace are rejected with
+	// the default Options.
+	for _, value := range []string{"multi\nline", " spaced", "cr\r"} {
+		cfg := &config.Config{}
+		cfg.Set("a", "key", value)
+		_, err := cfg.WriteTo(ioutil.Discard)
+		assert.Err(t, err, "%q was accepted", value)
+	}
+}
+
+func TestGlobalSection(t *testing.T) {
+	data := "# header\nname = app\n\n[log]\nlevel = Debug\n"
+	_, err := config.Load(strings.NewReader(data), nil)
+	assert.Err(t, err)
+
+	opts := config.Options{GlobalSection: "global"}
+	cfg, err := config.LoadOptions(strings.NewReader(data), opts, nil)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, cfg.GetOrPanic("global", "name"), "app")
+	assert.Equal(t, cfg.GetOrPanic("log", "level"), "Debug")
+
+	cfg.Set("global", "version", "1")
+	var buf bytes.Buffer
+	_, err = cfg.WriteTo(&buf)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, buf.String(), "# header\nname = app\nversion = 1\n\n[log]\nlevel = Debug\n")
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	data := "[Log]\nLevel = Debug\n[server]\nport = 80\naddr = ${Server:PORT}\n"
+	cfg, err := config.LoadOptions(strings.NewReader(data), config.Options{CaseInsensitive: true}, nil)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, cfg.GetOrPanic("LOG", "level"), "Debug")
+	assert.Equal(t, cfg.GetOrPanic("log", "LEVEL"), "Debug")
+	assert.FailIfNot(t, cfg.HasSection("LOG"))
+	_, ok := cfg.Sections["log"]
+	assert.FailIfNot(t, ok)
+	pos, ok := cfg.KeyPosition("LOG", "LEVEL")
+	assert.FailIfNot(t, ok)
+	assert.Equal(t, pos.Line, 2)
+	addr, err := cfg.Expand("SERVER", "Addr")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, addr, "80")
+
+	cfg.Set("LOG", "LEVEL", "Info")
+	assert.Equal(t, cfg.GetOrPanic("log", "level"), "Info")
+	var buf bytes.Buffer
+	_, err = cfg.WriteTo(&buf)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, buf.String(), strings.Replace(data, "Debug", "Info", 1))
+
+	cfg, err = config.LoadOptions(strings.NewReader("[log]\na = 1\n[LOG]\nb = 2\n"), config.Options{CaseInsensitive: true}, nil)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, cfg.Sections["log"], map[string]string{"a": "1", "b": "2"})
+}
+
+func TestDuplicates(t *testing.T) {
+	data := "[log]\nfile = a.log\nlevel = Debug\nfile = b.log\n"
+	load := func(policy config.DuplicatePolicy) (*config.Config, error) {
+		return config.LoadOptions(strings.NewReader(data), config.Options{Duplicates: policy}, nil)
+	}
+
+	cfg, err := load(config.DuplicateLast)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, cfg.GetOrPanic("log", "file"), "b.log")
+
+	_, err = load(config.DuplicateError)
+	assert.Err(t, err)
+	assert.Equal(t, err.Error(), "line 4, column 1: key 'file' was defined before in section 'log'")
+
+	cfg, err = load(config.DuplicateFirst)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, cfg.GetOrPanic("log", "file"), "a.log")
+	pos, _ := cfg.KeyPosition("log", "file")
+	assert.Equal(t, pos.Line, 2)
+	cfg.Set("log", "file", "c.log")
+	var buf bytes.Buffer
+	_, err = cfg.WriteTo(&buf)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, buf.String(), strings.Replace(data, "a.log", "c.log", 1))
+
+	cfg, err = load(config.DuplicateAccumulate)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, cfg.GetOrPanic("log", "file"), "b.log")
+	values, err := cfg.Values("log", "file")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, values, []string{"a.log", "b.log"})
+	values, err = cfg.Values("log", "level")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, values, []string{"Debug"})
+	_, err = cfg.Values("log", "missing")
+	assert.Err(t, err)
+
+	buf.Reset()
+	_, err = cfg.WriteTo(&buf)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, buf.String(), data)
+
+	cfg.Set("log", "file", "c.log")
+	values, _ = cfg.Values("log", "file")
+	assert.Equal(t, values, []string{"c.log"})
+	buf.Reset()
+	_, err = cfg.WriteTo(&buf)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, buf.String(), "[log]\nlevel = Debug\nfile = c.log\n")
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var (
+	// ErrSectionNotFound is returned, if a section does not exist.
+	ErrSectionNotFound = errors.New("section not found")
+	// ErrKeyNotFound is returned, if a key does not exist within a section.
+	ErrKeyNotFound = errors.New("key not found")
+)
+
+// Position is the position of a section, key or error within a loaded
+// configuration.
+type Position struct {
+	// File is the name of the file or empty, if the configuration was not
+	// loaded from a file.
+	File string
+	// Line is the line within the file, starting at 1.
+	Line int
+	// Column is the byte offset within the line, starting at 1. It is 0,
+	// if it is unknown.
+	Column int
+}
+
+// String returns the position in the form "file:line:column". The file and
+// column are omitted, if they are unknown.
+func (pos Position) String() string {
+	s := strconv.Itoa(pos.Line)
+	if pos.Column > 0 {
+		s += ":" + strconv.Itoa(pos.Column)
+	}
+	if pos.File != "" {
+		s = pos.File + ":" + s
+	}
+	return s
+}
+
+// ParseError is returned, if a configuration cannot be loaded due to an
+// invalid syntax or a failing include directive.
+type ParseError struct {
+	Position
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	msg := fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	if e.Column > 0 {
+		msg = fmt.Sprintf("line %d, column %d: %v", e.Line, e.Column, e.Err)
+	}
+	if e.File != "" {
+		msg = e.File + ": " + msg
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// NotFoundError is returned, if a section or key does not exist. It matches
+// ErrSectionNotFound or ErrKeyNotFound via errors.Is.
+type NotFoundError struct {
+	Section string
+	// Key is empty, if the section does not exist.
+	Key string
+}
+
+func (e *NotFoundError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("section '%s' does not exist", e.Section)
+	}
+	return fmt.Sprintf("key '%s' not found in section '%s'", e.Key, e.Section)
+}
+
+// Unwrap returns ErrSectionNotFound or ErrKeyNotFound.
+func (e *NotFoundError) Unwrap() error {
+	if e.Key == "" {
+		return ErrSectionNotFound
+	}
+	return ErrKeyNotFound
+}
+
+// SectionPosition gets the position of the first declaration of a section
+// within the loaded configuration. It returns false, if the section was not
+// loaded, e.g. because it was added via Set.
+func (cfg *Config) SectionPosition(section string) (Position, bool) {
+	section = cfg.foldSection(section)
+	for _, l := range cfg.lines {
+		if l.kind == lineSection && l.section == section && l.num > 0 {
+			return Position{l.file, l.num, l.col}, true
+		}
+	}
+	return Position{}, false
+}
+
+// KeyPosition gets the position of a key within the loaded configuration. If
+// the key was defined multiple times, the position of the definition in
+// effect is returned. It returns false, if the key was not loaded.
+func (cfg *Config) KeyPosition(section, key string) (Position, bool) {
+	section, key = cfg.foldSection(section), cfg.fold(key)
+	for idx := len(cfg.lines) - 1; idx >= 0; idx-- {
+		l := cfg.lines[idx]
+		if l.kind == lineKey && !l.ignored && l.section == section && l.key == key && l.num > 0 {
+			return Position{l.file, l.num, l.col}, true
+		}
+	}
+	return Position{}, false
+}
@@ -0,0 +1,73 @@
+package config_test
+
+import (
+	"errors"
+	"github.com/marcusva/gadget/config"
+	"github.com/marcusva/gadget/testing/assert"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPositions(t *testing.T) {
+	data := "# comment\n  [log]\n\tlevel = Debug\n"
+	cfg, err := config.Load(strings.NewReader(data), nil)
+	assert.FailOnErr(t, err)
+
+	pos, ok := cfg.SectionPosition("log")
+	assert.FailIfNot(t, ok)
+	assert.Equal(t, pos, config.Position{Line: 2, Column: 3})
+	assert.Equal(t, pos.String(), "2:3")
+
+	pos, ok = cfg.KeyPosition("log", "level")
+	assert.FailIfNot(t, ok)
+	assert.Equal(t, pos, config.Position{Line: 3, Column: 2})
+
+	cfg.Set("added", "key", "value")
+	_, ok = cfg.SectionPosition("added")
+	assert.FailIf(t, ok)
+	_, ok = cfg.KeyPosition("added", "key")
+	assert.FailIf(t, ok)
+
+	cfg, err = config.LoadFile("test/test.ini", nil)
+	assert.FailOnErr(t, err)
+	pos, ok = cfg.KeyPosition("example", "array")
+	assert.FailIfNot(t, ok)
+	assert.Equal(t, pos.String(), "test/test.ini:6:1")
+}
+
+func TestParseError(t *testing.T) {
+	_, err := config.Load(strings.NewReader("[log]\n  level Debug\n"), nil)
+	var perr *config.ParseError
+	assert.FailIfNot(t, errors.As(err, &perr), "invalid error type %T", err)
+	assert.Equal(t, perr.Position, config.Position{Line: 2, Column: 3})
+	assert.Equal(t, err.Error(), "line 2, column 3: key-value definition misses assignment")
+
+	_, err = config.LoadOptions(strings.NewReader("[log]\nlevel = \"1\" \"2\"\n"), config.ExtendedOptions, nil)
+	assert.FailIfNot(t, errors.As(err, &perr), "invalid error type %T", err)
+	assert.Equal(t, perr.Line, 2)
+}
+
+func TestNotFoundError(t *testing.T) {
+	cfg, err := config.Load(strings.NewReader("[log]\nlevel = Debug\n"), nil)
+	assert.FailOnErr(t, err)
+
+	_, err = cfg.Get("missing", "level")
+	assert.FailIfNot(t, errors.Is(err, config.ErrSectionNotFound), "invalid error %v", err)
+	assert.FailIf(t, errors.Is(err, config.ErrKeyNotFound), "invalid error %v", err)
+	assert.Equal(t, err.Error(), "section 'missing' does not exist")
+
+	_, err = cfg.Int("log", "missing")
+	assert.FailIfNot(t, errors.Is(err, config.ErrKeyNotFound), "invalid error %v", err)
+	var nerr *config.NotFoundError
+	assert.FailIfNot(t, errors.As(err, &nerr), "invalid error type %T", err)
+	assert.Equal(t, *nerr, config.NotFoundError{Section: "log", Key: "missing"})
+
+	_, err = cfg.AllFor("missing")
+	assert.FailIfNot(t, errors.Is(err, config.ErrSectionNotFound), "invalid error %v", err)
+
+	_, err = cfg.Int("log", "level")
+	var verr *config.ValueError
+	assert.FailIfNot(t, errors.As(err, &verr), "invalid error type %T", err)
+	assert.FailIfNot(t, errors.Is(err, strconv.ErrSyntax), "invalid error %v", err)
+}
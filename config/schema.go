@@ -0,0 +1,453 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ValueType is the type of a value described by a Schema.
+type ValueType int
+
+const (
+	// TypeString accepts any value.
+	TypeString ValueType = iota
+	// TypeInt accepts values understood by Int64.
+	TypeInt
+	// TypeUint accepts values understood by Uint64.
+	TypeUint
+	// TypeFloat accepts values understood by Float64.
+	TypeFloat
+	// TypeBool accepts values understood by Bool.
+	TypeBool
+	// TypeDuration accepts values understood by Duration.
+	TypeDuration
+	// TypeByteSize accepts values understood by ByteSize.
+	TypeByteSize
+	// TypeURL accepts values understood by URL.
+	TypeURL
+	// TypeIP accepts values understood by IP.
+	TypeIP
+	// TypeCIDR accepts values understood by CIDR.
+	TypeCIDR
+	// TypeFileMode accepts values understood by FileMode.
+	TypeFileMode
+	// TypeRegexp accepts values understood by Regexp.
+	TypeRegexp
+)
+
+// UnknownPolicy defines, how a Schema treats sections and keys, which are
+// not described by it.
+type UnknownPolicy int
+
+const (
+	// AllowUnknown accepts unknown sections and keys.
+	AllowUnknown UnknownPolicy = iota
+	// RejectUnknownKeys reports unknown keys of described sections, but
+	// accepts unknown sections.
+	RejectUnknownKeys
+	// RejectUnknown reports unknown sections and keys.
+	RejectUnknown
+)
+
+// Range is an inclusive range of numeric values. Integer values are compared
+// exactly against the bounds.
+type Range struct {
+	Min float64
+	Max float64
+}
+
+// contains checks, if the passed value is within the Range.
+func (r *Range) contains(value *big.Float) bool {
+	if math.IsNaN(r.Min) || math.IsNaN(r.Max) {
+		return false
+	}
+	return value.Cmp(big.NewFloat(r.Min)) >= 0 && value.Cmp(big.NewFloat(r.Max)) <= 0
+}
+
+// Key describes a single key of a Section.
+type Key struct {
+	// Type is the type of the value.
+	Type ValueType
+	// Required is true, if the key has to exist.
+	Required bool
+	// Range restricts the value of TypeInt, TypeUint, TypeFloat and
+	// TypeByteSize keys. For TypeDuration keys, it restricts the amount of
+	// seconds.
+	Range *Range
+	// Enum contains the allowed values. If it is empty, all values are
+	// allowed.
+	Enum []string
+	// Pattern has to match the whole value.
+	Pattern *regexp.Regexp
+	// Check is an additional check of the value.
+	Check func(value string) error
+}
+
+// Section describes a single section of a Schema.
+type Section struct {
+	// Required is true, if the section has to exist.
+	Required bool
+	// Keys describes the keys of the section.
+	Keys map[string]Key
+	// Exclusive contains groups of mutually exclusive keys, of which at most
+	// one may be set.
+	Exclusive [][]string
+}
+
+// Schema describes the sections and keys of a valid configuration.
+//
+//	schema := config.Schema{
+//		Sections: map[string]config.Section{
+//			"server": {
+//				Required: true,
+//				Keys: map[string]config.Key{
+//					"port": {Type: config.TypeInt, Required: true, Range: &config.Range{Min: 1, Max: 65535}},
+//					"mode": {Enum: []string{"http", "https"}},
+//				},
+//			},
+//		},
+//		Unknown: config.RejectUnknownKeys,
+//	}
+//	cfg, err := config.LoadFile("server.ini", schema.Validator())
+type Schema struct {
+	Sections map[string]Section
+	Unknown  UnknownPolicy
+}
+
+// Violation describes a single violation of a Schema.
+type Violation struct {
+	// Section is the section of the violation, which may be empty.
+	Section string
+	// Key is the key of the violation, which may be empty.
+	Key string
+	// File is the name of the file containing the line. It is empty, if the
+	// configuration was not loaded from a file.
+	File string
+	// Line is the line of the violation within the file or 0, if it is
+	// unknown.
+	Line int
+	// Err describes the violation.
+	Err error
+}
+
+func (v *Violation) Error() string {
+	var parts []string
+	if v.Line > 0 && v.File != "" {
+		parts = append(parts, v.File+": ")
+	}
+	if v.Line > 0 {
+		parts = append(parts, fmt.Sprintf("line %d: ", v.Line))
+	}
+	var location []string
+	if v.Section != "" {
+		location = append(location, fmt.Sprintf("section '%s'", v.Section))
+	}
+	if v.Key != "" {
+		location = append(location, fmt.Sprintf("key '%s'", v.Key))
+	}
+	if len(location) > 0 {
+		parts = append(parts, strings.Join(location, ", ")+": ")
+	}
+	return strings.Join(parts, "") + v.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (v *Violation) Unwrap() error {
+	return v.Err
+}
+
+// ValidationError contains all violations found by a Validator created from
+// a Schema or via Compose.
+type ValidationError struct {
+	Violations []*Violation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for idx, v := range e.Violations {
+		msgs[idx] = v.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Is checks, if any of the violations matches target, so that errors.Is
+// can be used with the ValidationError.
+func (e *ValidationError) Is(target error) bool {
+	for _, v := range e.Violations {
+		if errors.Is(v, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first violation, which matches target, and sets target to it,
+// so that errors.As can be used with the ValidationError.
+func (e *ValidationError) As(target interface{}) bool {
+	for _, v := range e.Violations {
+		if errors.As(v, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// add adds the passed error. The violations of a ValidationError are added
+// individually.
+func (e *ValidationError) add(err error) {
+	switch verr := err.(type) {
+	case nil:
+	case *ValidationError:
+		e.Violations = append(e.Violations, verr.Violations...)
+	case *Violation:
+		e.Violations = append(e.Violations, verr)
+	default:
+		e.Violations = append(e.Violations, &Violation{Err: err})
+	}
+}
+
+// err returns the ValidationError or nil, if there are no violations.
+func (e *ValidationError) err() error {
+	if len(e.Violations) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Validator creates a Validator, which checks a Config against the Schema.
+// The Validator reports all violations at once via a ValidationError.
+func (s Schema) Validator() Validator {
+	// The anchored copies of the patterns are created once per Validator,
+	// since the leftmost match of alternations may be shorter than the
+	// value, e.g. 'a|ab' for 'ab'.
+	patterns := make(map[*regexp.Regexp]*regexp.Regexp)
+	for _, sec := range s.Sections {
+		for _, k := range sec.Keys {
+			if k.Pattern != nil {
+				patterns[k.Pattern] = regexp.MustCompile("^(?:" + k.Pattern.String() + ")$")
+			}
+		}
+	}
+	return func(cfg *Config) error {
+		verr := &ValidationError{}
+		for _, name := range sortedKeys(s.Sections) {
+			verr.add(s.Sections[name].validate(cfg, name, s.Unknown, patterns))
+		}
+		if s.Unknown == RejectUnknown {
+			for _, name := range sortedKeys(cfg.Sections) {
+				if !s.describes(cfg, name) {
+					verr.add(cfg.sectionViolation(name, "", errors.New("unknown section")))
+				}
+			}
+		}
+		return verr.err()
+	}
+}
+
+// keyViolation creates a Violation at the position of the key.
+func (cfg *Config) keyViolation(section, key string, err error) *Violation {
+	file, line := cfg.posOf(section, key)
+	return &Violation{Section: section, Key: key, File: file, Line: line, Err: err}
+}
+
+// sectionViolation creates a Violation at the position of the section.
+func (cfg *Config) sectionViolation(section, key string, err error) *Violation {
+	file, line := cfg.posOfSection(section)
+	return &Violation{Section: section, Key: key, File: file, Line: line, Err: err}
+}
+
+// describes checks, if the Schema describes the section of the Config.
+func (s Schema) describes(cfg *Config, section string) bool {
+	for name := range s.Sections {
+		if cfg.foldSection(name) == section {
+			return true
+		}
+	}
+	return false
+}
+
+// describes checks, if the Section describes the key of the Config.
+func (sec Section) describes(cfg *Config, key string) bool {
+	for name := range sec.Keys {
+		if cfg.fold(name) == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (sec Section) validate(cfg *Config, name string, unknown UnknownPolicy, patterns map[*regexp.Regexp]*regexp.Regexp) error {
+	values, ok := cfg.Sections[cfg.foldSection(name)]
+	if !ok {
+		if sec.Required {
+			return &Violation{Section: name, Err: errors.New("missing section")}
+		}
+		return nil
+	}
+	verr := &ValidationError{}
+	for _, key := range sortedKeys(sec.Keys) {
+		desc := sec.Keys[key]
+		if _, ok := values[cfg.fold(key)]; !ok {
+			if desc.Required {
+				verr.add(cfg.sectionViolation(name, key, errors.New("missing key")))
+			}
+			continue
+		}
+		if err := desc.validate(cfg, name, key, patterns[desc.Pattern]); err != nil {
+			verr.add(cfg.keyViolation(name, key, err))
+		}
+	}
+	for _, group := range sec.Exclusive {
+		var set []string
+		for _, key := range group {
+			if _, ok := values[cfg.fold(key)]; ok {
+				set = append(set, key)
+			}
+		}
+		if len(set) > 1 {
+			err := fmt.Errorf("keys '%s' are mutually exclusive", strings.Join(set, "', '"))
+			verr.add(cfg.keyViolation(name, set[1], err))
+		}
+	}
+	if unknown != AllowUnknown {
+		for _, key := range sortedKeys(values) {
+			if !sec.describes(cfg, key) {
+				verr.add(cfg.keyViolation(name, key, errors.New("unknown key")))
+			}
+		}
+	}
+	return verr.err()
+}
+
+// validate checks the value of the key. pattern is the anchored copy of the
+// Pattern of the Key.
+func (k Key) validate(cfg *Config, section, key string, pattern *regexp.Regexp) error {
+	num, err := k.convert(cfg, section, key)
+	if err != nil {
+		if verr, ok := err.(*ValueError); ok {
+			return fmt.Errorf("invalid value '%s': %v", verr.Value, verr.Err)
+		}
+		return err
+	}
+	value, _ := cfg.Get(section, key)
+	// NaN is a float, which is not within any range.
+	nan := num == nil && k.Type == TypeFloat
+	if k.Range != nil && (nan || num != nil && !k.Range.contains(num)) {
+		return fmt.Errorf("value '%s' out of range [%v, %v]", value, k.Range.Min, k.Range.Max)
+	}
+	if len(k.Enum) > 0 {
+		found := false
+		for _, allowed := range k.Enum {
+			if value == allowed {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("value '%s' is not one of '%s'", value, strings.Join(k.Enum, "', '"))
+		}
+	}
+	if pattern != nil {
+		if !pattern.MatchString(value) {
+			return fmt.Errorf("value '%s' does not match '%s'", value, k.Pattern)
+		}
+	}
+	if k.Check != nil {
+		return k.Check(value)
+	}
+	return nil
+}
+
+// convert converts the value to the type of the Key and returns its numeric
+// value for range checks or nil, if the value is not numeric or NaN. Integers
+// are converted exactly, since float64 cannot represent all of them.
+func (k Key) convert(cfg *Config, section, key string) (*big.Float, error) {
+	var err error
+	switch k.Type {
+	case TypeInt:
+		var v int64
+		if v, err = cfg.Int64(section, key); err == nil {
+			return new(big.Float).SetInt64(v), nil
+		}
+	case TypeUint:
+		var v uint64
+		if v, err = cfg.Uint64(section, key); err == nil {
+			return new(big.Float).SetUint64(v), nil
+		}
+	case TypeFloat:
+		var v float64
+		if v, err = cfg.Float64(section, key); err == nil && !math.IsNaN(v) {
+			return big.NewFloat(v), nil
+		}
+	case TypeBool:
+		_, err = cfg.Bool(section, key)
+	case TypeDuration:
+		var d time.Duration
+		if d, err = cfg.Duration(section, key); err == nil {
+			return big.NewFloat(d.Seconds()), nil
+		}
+	case TypeByteSize:
+		var v uint64
+		if v, err = cfg.ByteSize(section, key); err == nil {
+			return new(big.Float).SetUint64(v), nil
+		}
+	case TypeURL:
+		_, err = cfg.URL(section, key)
+	case TypeIP:
+		_, err = cfg.IP(section, key)
+	case TypeCIDR:
+		_, err = cfg.CIDR(section, key)
+	case TypeFileMode:
+		_, err = cfg.FileMode(section, key)
+	case TypeRegexp:
+		_, err = cfg.Regexp(section, key)
+	}
+	return nil, err
+}
+
+// Compose creates a Validator, which runs all passed validators and reports
+// all of their errors. The violations of ValidationErrors are merged, other
+// errors are added as violations without a section and key. nil validators
+// are skipped.
+func Compose(validators ...Validator) Validator {
+	return func(cfg *Config) error {
+		verr := &ValidationError{}
+		for _, validate := range validators {
+			if validate != nil {
+				verr.add(validate(cfg))
+			}
+		}
+		return verr.err()
+	}
+}
+
+// sortedKeys returns the keys of a map with string keys in sorted order.
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]Section:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]Key:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]string:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]map[string]string:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
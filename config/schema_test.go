@@ -0,0 +1,199 @@
+package config_test
+
+import (
+	"errors"
+	"github.com/marcusva/gadget/config"
+	"github.com/marcusva/gadget/testing/assert"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const _schemaCfg = `[server]
+port = 70000
+mode = ftp
+host = Example.com
+cert = server.pem
+insecure = true
+timeout = 2m
+extra = 1
+
+[unknown]
+key = value
+`
+
+var _schema = config.Schema{
+	Sections: map[string]config.Section{
+		"server": {
+			Required: true,
+			Keys: map[string]config.Key{
+				"port":     {Type: config.TypeInt, Required: true, Range: &config.Range{Min: 1, Max: 65535}},
+				"mode":     {Enum: []string{"http", "https"}},
+				"host":     {Pattern: regexp.MustCompile("[a-z.]+")},
+				"cert":     {},
+				"insecure": {Type: config.TypeBool},
+				"timeout":  {Type: config.TypeDuration, Range: &config.Range{Min: 1, Max: 60}},
+				"workers":  {Type: config.TypeInt, Required: true},
+			},
+			Exclusive: [][]string{{"cert", "insecure"}},
+		},
+		"db": {Required: true},
+	},
+	Unknown: config.RejectUnknown,
+}
+
+func TestSchema(t *testing.T) {
+	_, err := config.Load(strings.NewReader(_schemaCfg), _schema.Validator())
+	assert.Err(t, err)
+	verr, ok := err.(*config.ValidationError)
+	assert.FailIfNot(t, ok, "invalid error type %T", err)
+
+	expected := []string{
+		"section 'db': missing section",
+		"line 4: section 'server', key 'host': value 'Example.com' does not match '[a-z.]+'",
+		"line 3: section 'server', key 'mode': value 'ftp' is not one of 'http', 'https'",
+		"line 2: section 'server', key 'port': value '70000' out of range [1, 65535]",
+		"line 7: section 'server', key 'timeout': value '2m' out of range [1, 60]",
+		"line 1: section 'server', key 'workers': missing key",
+		"line 6: section 'server', key 'insecure': keys 'cert', 'insecure' are mutually exclusive",
+		"line 8: section 'server', key 'extra': unknown key",
+		"line 10: section 'unknown': unknown section",
+	}
+	assert.Equal(t, len(verr.Violations), len(expected))
+	for idx, msg := range expected {
+		assert.Equal(t, verr.Violations[idx].Error(), msg)
+	}
+	assert.Equal(t, err.Error(), strings.Join(expected, "\n"))
+
+	valid := "[server]\nport = 80\nworkers = 4\nmode = http\n[db]\n"
+	_, err = config.Load(strings.NewReader(valid), _schema.Validator())
+	assert.FailOnErr(t, err)
+}
+
+func TestSchemaTypes(t *testing.T) {
+	schema := config.Schema{
+		Sections: map[string]config.Section{
+			"types": {Keys: map[string]config.Key{
+				"int":   {Type: config.TypeInt},
+				"size":  {Type: config.TypeByteSize, Range: &config.Range{Max: 1024}},
+				"ip":    {Type: config.TypeIP},
+				"check": {Check: func(value string) error { return errors.New("always fails") }},
+			}},
+		},
+	}
+	data := "[types]\nint = abc\nsize = 1KiB\nip = 1.2.3\ncheck = x\nunknown = x\n"
+	_, err := config.Load(strings.NewReader(data), schema.Validator())
+	assert.Err(t, err)
+	verr := err.(*config.ValidationError)
+	assert.Equal(t, len(verr.Violations), 3)
+	assert.Equal(t, verr.Violations[0].Error(), "line 5: section 'types', key 'check': always fails")
+	assert.Equal(t, verr.Violations[1].Error(), "line 2: section 'types', key 'int': invalid value 'abc': invalid syntax")
+	assert.Equal(t, verr.Violations[2].Error(), "line 4: section 'types', key 'ip': invalid value '1.2.3': invalid IP address")
+}
+
+func TestCompose(t *testing.T) {
+	errFailed := errors.New("failed")
+	failing := func(cfg *config.Config) error { return errFailed }
+	schema := config.Schema{Sections: map[string]config.Section{"missing": {Required: true}}}
+
+	validate := config.Compose(config.NoValidate, nil, failing, schema.Validator())
+	err := validate(&config.Config{})
+	assert.Err(t, err)
+	verr := err.(*config.ValidationError)
+	assert.Equal(t, len(verr.Violations), 2)
+	assert.Equal(t, verr.Violations[0].Err, errFailed)
+	assert.Equal(t, verr.Violations[1].Error(), "section 'missing': missing section")
+
+	assert.FailOnErr(t, config.Compose(config.NoValidate)(&config.Config{}))
+}
+
+func TestSchemaPattern(t *testing.T) {
+	schema := config.Schema{
+		Sections: map[string]config.Section{
+			"a": {Keys: map[string]config.Key{"x": {Pattern: regexp.MustCompile("a|ab")}}},
+		},
+	}
+	for value, valid := range map[string]bool{"a": true, "ab": true, "abc": false, "b": false} {
+		_, err := config.Load(strings.NewReader("[a]\nx = "+value+"\n"), schema.Validator())
+		assert.Equal(t, err == nil, valid, "value '%s': %v", value, err)
+	}
+}
+
+func TestSchemaRangeInt(t *testing.T) {
+	schema := config.Schema{
+		Sections: map[string]config.Section{
+			"a": {Keys: map[string]config.Key{
+				"int":  {Type: config.TypeInt, Range: &config.Range{Min: 0, Max: 1 << 53}},
+				"uint": {Type: config.TypeUint, Range: &config.Range{Max: 1 << 53}},
+			}},
+		},
+	}
+	values := map[string]bool{
+		"int = 9007199254740992":  true,
+		"int = 9007199254740993":  false,
+		"int = -1":                false,
+		"uint = 9007199254740992": true,
+		"uint = 9007199254740993": false,
+	}
+	for line, valid := range values {
+		_, err := config.Load(strings.NewReader("[a]\n"+line+"\n"), schema.Validator())
+		assert.Equal(t, err == nil, valid, "'%s': %v", line, err)
+	}
+}
+
+func TestValidationErrorIs(t *testing.T) {
+	errInvalid := errors.New("invalid")
+	schema := config.Schema{
+		Sections: map[string]config.Section{
+			"a": {Keys: map[string]config.Key{
+				"x": {Type: config.TypeInt},
+				"y": {Check: func(value string) error { return errInvalid }},
+			}},
+		},
+	}
+	_, err := config.Load(strings.NewReader("[a]\nx = 1\ny = 2\n"), schema.Validator())
+	assert.Err(t, err)
+	assert.FailIfNot(t, errors.Is(err, errInvalid), "invalid error %v", err)
+	assert.FailIf(t, errors.Is(err, config.ErrKeyNotFound), "invalid error %v", err)
+	var v *config.Violation
+	assert.FailIfNot(t, errors.As(err, &v), "invalid error type %T", err)
+	assert.Equal(t, v.Key, "y")
+}
+
+func TestSchemaCaseInsensitive(t *testing.T) {
+	schema := config.Schema{
+		Sections: map[string]config.Section{
+			"Server": {
+				Required: true,
+				Keys: map[string]config.Key{
+					"Port": {Type: config.TypeInt, Required: true, Range: &config.Range{Min: 1, Max: 65535}},
+				},
+			},
+		},
+		Unknown: config.RejectUnknown,
+	}
+	opts := config.Options{CaseInsensitive: true}
+	_, err := config.LoadOptions(strings.NewReader("[SERVER]\nPORT = 80\n"), opts, schema.Validator())
+	assert.FailOnErr(t, err)
+
+	_, err = config.LoadOptions(strings.NewReader("[server]\nport = 0\n"), opts, schema.Validator())
+	assert.Err(t, err)
+	assert.Equal(t, err.Error(), "line 2: section 'Server', key 'Port': value '0' out of range [1, 65535]")
+}
+
+func TestSchemaRangeNaN(t *testing.T) {
+	schema := config.Schema{
+		Sections: map[string]config.Section{
+			"a": {Keys: map[string]config.Key{
+				"f": {Type: config.TypeFloat, Range: &config.Range{Min: 0, Max: 1}},
+				"g": {Type: config.TypeFloat},
+			}},
+		},
+	}
+	_, err := config.Load(strings.NewReader("[a]\nf = NaN\n"), schema.Validator())
+	assert.Err(t, err)
+	assert.Equal(t, err.Error(), "line 2: section 'a', key 'f': value 'NaN' out of range [0, 1]")
+
+	_, err = config.Load(strings.NewReader("[a]\nf = 0.5\ng = NaN\n"), schema.Validator())
+	assert.FailOnErr(t, err)
+}
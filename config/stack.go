@@ -0,0 +1,251 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SourceKind describes the kind of source, a value was taken from.
+type SourceKind int
+
+const (
+	// SourceDefault marks built-in default values.
+	SourceDefault SourceKind = iota
+	// SourceFile marks values of a configuration file.
+	SourceFile
+	// SourceEnv marks values of environment variables.
+	SourceEnv
+	// SourceFlag marks values of command-line flags.
+	SourceFlag
+)
+
+// Origin describes, where a value of a Stack was taken from.
+type Origin struct {
+	// Kind is the kind of the source.
+	Kind SourceKind
+	// Name is the name of the source, which is the file name for files,
+	// the variable name for environment variables and the flag name for
+	// command-line flags.
+	Name string
+	// Line is the line of the value within the file or 0, if it is unknown.
+	Line int
+}
+
+// String returns the Origin in a human-readable form, such as
+// "/etc/app.ini:12", "env APP_DB_HOST" or "flag -db.host".
+func (o Origin) String() string {
+	switch o.Kind {
+	case SourceDefault:
+		if o.Name == "" {
+			return "default"
+		}
+		return "default " + o.Name
+	case SourceEnv:
+		return "env " + o.Name
+	case SourceFlag:
+		return "flag -" + o.Name
+	default:
+		if o.Line > 0 {
+			return fmt.Sprintf("%s:%d", o.Name, o.Line)
+		}
+		return o.Name
+	}
+}
+
+// Value is a value of a Stack with its Origin.
+type Value struct {
+	Value  string
+	Origin Origin
+}
+
+// layer is a single source of a Stack.
+type layer struct {
+	kind SourceKind
+	name string
+	cfg  *Config
+	// names contains the variable or flag names of the values.
+	names map[sectionKey]string
+}
+
+// get gets the Value of a key of the layer. If fold is true, the section and
+// key are matched case-insensitively.
+func (l *layer) get(section, key string, fold bool) (Value, bool) {
+	section, key = l.cfg.foldSection(section), l.cfg.fold(key)
+	if value, ok := l.cfg.Sections[section][key]; ok || !fold {
+		return Value{value, l.origin(section, key)}, ok
+	}
+	for name, values := range l.cfg.Sections {
+		if !strings.EqualFold(name, section) {
+			continue
+		}
+		for k, value := range values {
+			if strings.EqualFold(k, key) {
+				return Value{value, l.origin(name, k)}, true
+			}
+		}
+	}
+	return Value{}, false
+}
+
+// origin gets the Origin of a key of the layer.
+func (l *layer) origin(section, key string) Origin {
+	sk := sectionKey{section, key}
+	if name, ok := l.names[sk]; ok {
+		return Origin{Kind: l.kind, Name: name}
+	}
+	if variable, ok := l.cfg.FromEnv(section, key); ok {
+		return Origin{Kind: SourceEnv, Name: variable}
+	}
+	file, line := l.cfg.posOf(section, key)
+	if file == "" {
+		file = l.name
+	}
+	return Origin{Kind: l.kind, Name: file, Line: line}
+}
+
+// Stack combines multiple configuration sources, such as built-in defaults,
+// system and user configuration files, environment variables and
+// command-line flags. Sources added later take precedence over the ones added
+// before, thus they are usually added in the following order:
+//
+//	stack := config.NewStack()
+//	stack.AddDefaults(defaults)
+//	stack.AddFile("/etc/app.ini", true)
+//	stack.AddFile(filepath.Join(home, ".app.ini"), true)
+//	stack.AddEnv(config.EnvOptions{Prefix: "APP"})
+//	stack.AddFlags(flag.CommandLine)
+//	cfg := stack.Config()
+//
+// For each value, the Stack can report its Origin via Lookup and Values.
+type Stack struct {
+	layers []*layer
+}
+
+// NewStack creates a new, empty Stack.
+func NewStack() *Stack {
+	return &Stack{}
+}
+
+// AddDefaults adds the built-in default values of cfg to the Stack. cfg can
+// be created via Marshal from a struct containing the default values.
+func (s *Stack) AddDefaults(cfg *Config) {
+	s.layers = append(s.layers, &layer{kind: SourceDefault, cfg: cfg})
+}
+
+// AddConfig adds an already loaded Config to the Stack. The name is used as
+// Origin for values, which were not loaded from a file.
+func (s *Stack) AddConfig(name string, cfg *Config) {
+	s.layers = append(s.layers, &layer{kind: SourceFile, name: name, cfg: cfg})
+}
+
+// AddFile loads the passed file via LoadFile and adds it to the Stack. If
+// optional is true, a missing file is skipped.
+func (s *Stack) AddFile(filename string, optional bool) error {
+	cfg, err := LoadFile(filename, nil)
+	if err != nil {
+		if optional && os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	s.AddConfig(filename, cfg)
+	return nil
+}
+
+// AddEnv adds the environment variables to the Stack. The variables are
+// looked up via ApplyEnv for all keys of the sources added before.
+func (s *Stack) AddEnv(opts EnvOptions) {
+	cfg := s.Config()
+	l := &layer{kind: SourceEnv, cfg: newConfig(), names: make(map[sectionKey]string)}
+	l.cfg.opts.CaseInsensitive = cfg.opts.CaseInsensitive
+	for _, ov := range cfg.ApplyEnv(opts) {
+		l.cfg.Set(ov.Section, ov.Key, cfg.Sections[ov.Section][ov.Key])
+		l.names[sectionKey{ov.Section, ov.Key}] = ov.Variable
+	}
+	s.layers = append(s.layers, l)
+}
+
+// AddFlags adds the command-line flags of fs, which were set explicitly, to
+// the Stack. The flags have to be named "section.key". The name is split at
+// the last dot, flags without a dot are ignored. fs must have been parsed
+// before.
+func (s *Stack) AddFlags(fs *flag.FlagSet) {
+	l := &layer{kind: SourceFlag, cfg: newConfig(), names: make(map[sectionKey]string)}
+	fs.Visit(func(f *flag.Flag) {
+		sep := strings.LastIndexByte(f.Name, '.')
+		if sep <= 0 || sep == len(f.Name)-1 {
+			return
+		}
+		section, key := f.Name[:sep], f.Name[sep+1:]
+		l.cfg.Set(section, key, f.Value.String())
+		l.names[sectionKey{section, key}] = f.Name
+	})
+	s.layers = append(s.layers, l)
+}
+
+// Lookup gets the value in effect for a certain key within the specified
+// section together with its Origin.
+func (s *Stack) Lookup(section, key string) (Value, bool) {
+	fold := s.caseInsensitive()
+	for idx := len(s.layers) - 1; idx >= 0; idx-- {
+		l := s.layers[idx]
+		if value, ok := l.get(section, key, fold); ok {
+			return value, true
+		}
+	}
+	return Value{}, false
+}
+
+// Values gets all values of a certain key within the specified section from
+// the sources of the Stack, ordered by increasing precedence. The last value
+// is the one in effect.
+func (s *Stack) Values(section, key string) []Value {
+	var result []Value
+	fold := s.caseInsensitive()
+	for _, l := range s.layers {
+		if value, ok := l.get(section, key, fold); ok {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// caseInsensitive checks, if any of the sources was loaded with the
+// CaseInsensitive option.
+func (s *Stack) caseInsensitive() bool {
+	for _, l := range s.layers {
+		if l.cfg.opts.CaseInsensitive {
+			return true
+		}
+	}
+	return false
+}
+
+// Config merges the sources of the Stack into a new Config, which contains
+// the values in effect. If any of the sources was loaded with the
+// CaseInsensitive option, the new Config is case-insensitive as well.
+func (s *Stack) Config() *Config {
+	cfg := newConfig()
+	cfg.opts.CaseInsensitive = s.caseInsensitive()
+	for _, l := range s.layers {
+		for name, parent := range l.cfg.subsections {
+			cfg.subsections[name] = parent
+		}
+	}
+	for _, l := range s.layers {
+		for section, values := range l.cfg.Sections {
+			section = cfg.foldSection(section)
+			opts, ok := cfg.Sections[section]
+			if !ok {
+				opts = make(map[string]string)
+				cfg.Sections[section] = opts
+			}
+			for key, value := range values {
+				opts[cfg.fold(key)] = value
+			}
+		}
+	}
+	return cfg
+}
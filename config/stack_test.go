@@ -0,0 +1,110 @@
+package config_test
+
+import (
+	"flag"
+	"github.com/marcusva/gadget/config"
+	"github.com/marcusva/gadget/testing/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStack(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "gadget-cfgtest")
+	assert.FailOnErr(t, err)
+	defer os.RemoveAll(dir)
+
+	system := filepath.Join(dir, "system.ini")
+	user := filepath.Join(dir, "user.ini")
+	writeFiles(t, dir, map[string]string{
+		"system.ini": "[db]\nhost = db.example.com\nport = 5432\n\n[log]\nlevel = Error\n",
+		"user.ini":   "[log]\n# more output\nlevel = Info\n",
+	})
+
+	defaults, err := config.Marshal(&struct {
+		DB struct {
+			Host string `ini:"host"`
+			Port int    `ini:"port"`
+			User string `ini:"user"`
+		} `ini:"db"`
+	}{})
+	assert.FailOnErr(t, err)
+	defaults.Set("db", "user", "admin")
+
+	os.Setenv("STACKTEST_DB_USER", "envuser")
+	defer os.Unsetenv("STACKTEST_DB_USER")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("db.port", "", "database port")
+	fs.String("log.level", "", "log level")
+	fs.Bool("verbose", false, "verbose output")
+	assert.FailOnErr(t, fs.Parse([]string{"-db.port", "6543", "-verbose"}))
+
+	stack := config.NewStack()
+	stack.AddDefaults(defaults)
+	assert.FailOnErr(t, stack.AddFile(system, false))
+	assert.FailOnErr(t, stack.AddFile(user, false))
+	assert.FailOnErr(t, stack.AddFile(filepath.Join(dir, "missing.ini"), true))
+	assert.Err(t, stack.AddFile(filepath.Join(dir, "missing.ini"), false))
+	stack.AddEnv(config.EnvOptions{Prefix: "STACKTEST"})
+	stack.AddFlags(fs)
+
+	checks := []struct {
+		section, key, value, origin string
+	}{
+		{"db", "host", "db.example.com", system + ":2"},
+		{"db", "port", "6543", "flag -db.port"},
+		{"db", "user", "envuser", "env STACKTEST_DB_USER"},
+		{"log", "level", "Info", user + ":3"},
+	}
+	for _, c := range checks {
+		v, ok := stack.Lookup(c.section, c.key)
+		assert.FailIfNot(t, ok, "missing %s.%s", c.section, c.key)
+		assert.Equal(t, v.Value, c.value)
+		assert.Equal(t, v.Origin.String(), c.origin)
+	}
+	_, ok := stack.Lookup("db", "missing")
+	assert.FailIf(t, ok)
+
+	values := stack.Values("db", "port")
+	assert.Equal(t, len(values), 3)
+	assert.Equal(t, values[0].Value, "0")
+	assert.Equal(t, values[0].Origin.Kind, config.SourceDefault)
+	assert.Equal(t, values[0].Origin.String(), "default")
+	assert.Equal(t, values[1].Value, "5432")
+	assert.Equal(t, values[2].Value, "6543")
+
+	cfg := stack.Config()
+	assert.Equal(t, cfg.GetOrPanic("db", "port"), "6543")
+	assert.Equal(t, cfg.GetOrPanic("db", "user"), "envuser")
+	assert.Equal(t, cfg.GetOrPanic("log", "level"), "Info")
+	assert.FailIf(t, cfg.HasSection("verbose"))
+}
+
+func TestStackCaseInsensitive(t *testing.T) {
+	cfg, err := config.LoadOptions(strings.NewReader("[DB]\nHost = localhost\n"), config.Options{CaseInsensitive: true}, nil)
+	assert.FailOnErr(t, err)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("db.host", "", "database host")
+	assert.FailOnErr(t, fs.Parse([]string{"-db.host", "db.example.com"}))
+
+	stack := config.NewStack()
+	stack.AddConfig("app.ini", cfg)
+	value, ok := stack.Lookup("Db", "HOST")
+	assert.FailIfNot(t, ok)
+	assert.Equal(t, value.Value, "localhost")
+	assert.Equal(t, value.Origin.String(), "app.ini:2")
+
+	stack.AddFlags(fs)
+	value, ok = stack.Lookup("db", "host")
+	assert.FailIfNot(t, ok)
+	assert.Equal(t, value.Value, "db.example.com")
+	value, ok = stack.Lookup("DB", "Host")
+	assert.FailIfNot(t, ok)
+	assert.Equal(t, value.Origin.String(), "flag -db.host")
+	assert.Equal(t, len(stack.Values("DB", "Host")), 2)
+	assert.Equal(t, stack.Config().GetOrPanic("DB", "HOST"), "db.example.com")
+}
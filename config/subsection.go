@@ -0,0 +1,194 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// sectionName gets the name of a section from the contents of its header.
+// Git-style subsections, such as [remote "origin"], are converted to the
+// dotted form remote.origin and the name of their parent is returned as
+// well. Only the name of the parent section is subject to the
+// CaseInsensitive option.
+func (cfg *Config) sectionName(header string) (string, string, error) {
+	quote := strings.IndexByte(header, '"')
+	if quote == -1 {
+		return cfg.fold(header), "", nil
+	}
+	parent := strings.TrimSpace(header[:quote])
+	if parent == "" || strings.ContainsAny(parent, " \t") {
+		return "", "", errors.New("invalid subsection header")
+	}
+	var buf strings.Builder
+	for i := quote + 1; i < len(header); i++ {
+		switch c := header[i]; c {
+		case '\\':
+			if i+1 == len(header) {
+				return "", "", errors.New("invalid subsection header")
+			}
+			i++
+			buf.WriteByte(header[i])
+		case '"':
+			if i != len(header)-1 {
+				return "", "", errors.New("unexpected text after subsection name")
+			}
+			if buf.Len() == 0 {
+				return "", "", errors.New("invalid, empty subsection name")
+			}
+			parent = cfg.fold(parent)
+			return parent + "." + buf.String(), parent, nil
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return "", "", errors.New("subsection name misses the closing quote")
+}
+
+// foldSection gets the name of a section subject to the CaseInsensitive
+// option. For git-style subsections, only the name of the parent section is
+// folded.
+func (cfg *Config) foldSection(section string) string {
+	if !cfg.opts.CaseInsensitive {
+		return section
+	}
+	if _, ok := cfg.subsections[section]; ok {
+		return section
+	}
+	for name, parent := range cfg.subsections {
+		if len(name) == len(section) && strings.EqualFold(section[:len(parent)], parent) &&
+			section[len(parent):] == name[len(parent):] {
+			return name
+		}
+	}
+	return strings.ToLower(section)
+}
+
+// Subsections gets the names of the direct subsections of the parent
+// section in sorted order. Subsections are sections named "parent.name",
+// which are declared as [parent.name] or [parent "name"]. For nested
+// sections, such as [parent.name.sub], only "name" is returned. Names of
+// git-style subsections are returned as is, even if they contain dots.
+func (cfg *Config) Subsections(parent string) []string {
+	parent = cfg.foldSection(parent)
+	prefix := parent + "."
+	found := make(map[string]bool)
+	var names []string
+	for section := range cfg.Sections {
+		if !strings.HasPrefix(section, prefix) || len(section) == len(prefix) {
+			continue
+		}
+		name := section[len(prefix):]
+		if p, ok := cfg.subsections[section]; ok {
+			if p != parent {
+				// A git-style subsection of another parent.
+				continue
+			}
+		} else if dot := strings.IndexByte(name, '.'); dot > 0 {
+			name = name[:dot]
+		}
+		if !found[name] {
+			found[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// View provides access to a single section and its subsections.
+type View struct {
+	cfg  *Config
+	name string
+}
+
+// View gets a View for the passed section. The section does not need to
+// exist.
+//
+//	for _, name := range cfg.Subsections("backend") {
+//		backend := cfg.View("backend").Sub(name)
+//		url, err := backend.Get("url")
+//		...
+//	}
+func (cfg *Config) View(section string) *View {
+	return &View{cfg: cfg, name: cfg.foldSection(section)}
+}
+
+// Name gets the full name of the section of the View.
+func (v *View) Name() string {
+	return v.name
+}
+
+// Exists checks, if the section of the View exists.
+func (v *View) Exists() bool {
+	return v.cfg.HasSection(v.name)
+}
+
+// Get gets a value for a certain key within the section of the View.
+func (v *View) Get(key string) (string, error) {
+	return v.cfg.Get(v.name, key)
+}
+
+// GetDefault gets a value for a certain key within the section of the View.
+// If the section or key could not be found, the provided default value will
+// be returned.
+func (v *View) GetDefault(key, def string) string {
+	return v.cfg.GetDefault(v.name, key, def)
+}
+
+// Keys gets the keys of the section of the View in sorted order.
+func (v *View) Keys() []string {
+	var keys []string
+	for key := range v.cfg.Sections[v.name] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Subsections gets the names of the direct subsections of the section of
+// the View.
+func (v *View) Subsections() []string {
+	return v.cfg.Subsections(v.name)
+}
+
+// Sub gets a View for the subsection with the passed name.
+func (v *View) Sub(name string) *View {
+	return &View{cfg: v.cfg, name: v.cfg.foldSection(v.name + "." + name)}
+}
+
+// Unmarshal decodes the section of the View into the struct pointed to by
+// out as described for Config.Unmarshal for structs representing a section.
+func (v *View) Unmarshal(out interface{}) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("Unmarshal requires a non-nil pointer to a struct")
+	}
+	return v.cfg.decodeSection(v.name, rv.Elem())
+}
+
+// isSectionMap checks, if the passed type is decoded from all subsections of
+// a section.
+func isSectionMap(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && t.Key().Kind() == reflect.String && isSection(t.Elem())
+}
+
+// decodeSections fills the map value fv with the subsections of parent.
+func (cfg *Config) decodeSections(parent string, fv reflect.Value) error {
+	names := cfg.Subsections(parent)
+	if len(names) == 0 {
+		return nil
+	}
+	if fv.IsNil() {
+		fv.Set(reflect.MakeMap(fv.Type()))
+	}
+	for _, name := range names {
+		ev := reflect.New(fv.Type().Elem()).Elem()
+		if err := cfg.decodeSection(cfg.foldSection(parent)+"."+name, ev); err != nil {
+			return err
+		}
+		fv.SetMapIndex(reflect.ValueOf(name).Convert(fv.Type().Key()), ev)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package config_test
+
+import (
+	"github.com/marcusva/gadget/config"
+	"github.com/marcusva/gadget/testing/assert"
+	"strings"
+	"testing"
+)
+
+const _subsections = `[remote "origin"]
+url = https://example.com/origin.git
+
+[remote "with \"quotes\""]
+url = https://example.com/quotes.git
+
+[server]
+port = 80
+
+[server.http]
+timeout = 30s
+
+[server.http.tls]
+cert = server.pem
+
+[backend "api"]
+url = http://localhost:8080
+weight = 2
+
+[backend "web"]
+url = http://localhost:8081
+`
+
+func TestSubsections(t *testing.T) {
+	cfg, err := config.Load(strings.NewReader(_subsections), nil)
+	assert.FailOnErr(t, err)
+
+	assert.Equal(t, cfg.GetOrPanic("remote.origin", "url"), "https://example.com/origin.git")
+	assert.Equal(t, cfg.GetOrPanic("remote.with \"quotes\"", "url"), "https://example.com/quotes.git")
+	assert.Equal(t, cfg.Subsections("remote"), []string{"origin", "with \"quotes\""})
+	assert.Equal(t, cfg.Subsections("server"), []string{"http"})
+	assert.Equal(t, cfg.Subsections("server.http"), []string{"tls"})
+	assert.Equal(t, len(cfg.Subsections("missing")), 0)
+
+	server := cfg.View("server")
+	assert.FailIfNot(t, server.Exists())
+	assert.Equal(t, server.Keys(), []string{"port"})
+	assert.Equal(t, server.Subsections(), []string{"http"})
+	tls := server.Sub("http").Sub("tls")
+	assert.Equal(t, tls.Name(), "server.http.tls")
+	assert.Equal(t, tls.GetDefault("cert", ""), "server.pem")
+	_, err = tls.Get("key")
+	assert.Err(t, err)
+	assert.FailIf(t, cfg.View("missing").Exists())
+
+	for _, header := range []string{`[ "x"]`, `[a "x" b]`, `[a "x]`, `[a ""]`, `[a b "x"]`} {
+		_, err := config.Load(strings.NewReader(header+"\n"), nil)
+		assert.Err(t, err, header)
+	}
+	cfg, err = config.Load(strings.NewReader("[a \"x\"]\nb = 1\n[a.x]\nc = 2\n"), nil)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, cfg.Sections["a.x"], map[string]string{"b": "1", "c": "2"})
+}
+
+func TestUnmarshalSubsections(t *testing.T) {
+	cfg, err := config.Load(strings.NewReader(_subsections), nil)
+	assert.FailOnErr(t, err)
+
+	type backend struct {
+		URL    string `ini:"url"`
+		Weight int    `ini:"weight" default:"1"`
+	}
+	var settings struct {
+		Backends map[string]backend `ini:"backend"`
+		Server   struct {
+			Port int `ini:"port"`
+			HTTP struct {
+				Timeout string `ini:"timeout"`
+				TLS     *struct {
+					Cert string `ini:"cert"`
+				} `ini:"tls"`
+			} `ini:"http"`
+		} `ini:"server"`
+	}
+	assert.FailOnErr(t, cfg.Unmarshal(&settings))
+	assert.Equal(t, settings.Backends, map[string]backend{
+		"api": {"http://localhost:8080", 2},
+		"web": {"http://localhost:8081", 1},
+	})
+	assert.Equal(t, settings.Server.Port, 80)
+	assert.Equal(t, settings.Server.HTTP.Timeout, "30s")
+	assert.NotNil(t, settings.Server.HTTP.TLS)
+	assert.Equal(t, settings.Server.HTTP.TLS.Cert, "server.pem")
+
+	var b backend
+	assert.FailOnErr(t, cfg.View("backend").Sub("api").Unmarshal(&b))
+	assert.Equal(t, b, backend{"http://localhost:8080", 2})
+	assert.Err(t, cfg.View("backend").Unmarshal(b))
+}
+
+func TestSubsectionsDotted(t *testing.T) {
+	data := "[url \"https://a.example.com\"]\ninsteadOf = a\n[url.local]\npath = /tmp\n[url.local.sub]\nx = 1\n"
+	cfg, err := config.Load(strings.NewReader(data), nil)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, cfg.Subsections("url"), []string{"https://a.example.com", "local"})
+	assert.Equal(t, len(cfg.Subsections("url.https://a")), 0)
+	assert.Equal(t, cfg.View("url").Sub("https://a.example.com").GetDefault("insteadOf", ""), "a")
+
+	var settings struct {
+		URLs map[string]struct {
+			InsteadOf string `ini:"insteadOf"`
+		} `ini:"url"`
+	}
+	assert.FailOnErr(t, cfg.Unmarshal(&settings))
+	assert.Equal(t, len(settings.URLs), 2)
+	assert.Equal(t, settings.URLs["https://a.example.com"].InsteadOf, "a")
+}
+
+func TestSubsectionsCaseInsensitive(t *testing.T) {
+	data := "[Remote \"Origin\"]\nURL = https://example.com/origin.git\n[Server.HTTP]\nPort = 80\n"
+	cfg, err := config.LoadOptions(strings.NewReader(data), config.Options{CaseInsensitive: true}, nil)
+	assert.FailOnErr(t, err)
+
+	assert.Equal(t, cfg.Subsections("REMOTE"), []string{"Origin"})
+	assert.Equal(t, cfg.GetOrPanic("remote.Origin", "url"), "https://example.com/origin.git")
+	assert.Equal(t, cfg.GetOrPanic("REMOTE.Origin", "Url"), "https://example.com/origin.git")
+	assert.FailIfNot(t, cfg.HasSection("Remote.Origin"))
+	assert.FailIf(t, cfg.HasSection("remote.origin"))
+
+	url, err := cfg.View("remote").Sub("Origin").Get("url")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, url, "https://example.com/origin.git")
+
+	http := cfg.View("SERVER").Sub("Http")
+	assert.Equal(t, http.Name(), "server.http")
+	assert.Equal(t, http.Keys(), []string{"port"})
+}
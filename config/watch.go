@@ -0,0 +1,349 @@
+package config
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ChangeKind describes, how a key was changed.
+type ChangeKind int
+
+const (
+	// KeyAdded marks a key, which did not exist before.
+	KeyAdded ChangeKind = iota
+	// KeyRemoved marks a key, which does not exist anymore.
+	KeyRemoved
+	// KeyModified marks a key with a changed value.
+	KeyModified
+)
+
+// String returns the name of the ChangeKind.
+func (kind ChangeKind) String() string {
+	switch kind {
+	case KeyAdded:
+		return "added"
+	case KeyRemoved:
+		return "removed"
+	default:
+		return "modified"
+	}
+}
+
+// Change describes a changed key between two configurations.
+type Change struct {
+	Section string
+	Key     string
+	Kind    ChangeKind
+	// Old is the previous value, which is empty for added keys.
+	Old string
+	// New is the current value, which is empty for removed keys.
+	New string
+}
+
+// Diff compares two configurations and returns the added, removed and
+// modified keys sorted by section and key. Keys with multiple values, such
+// as key[], are modified, if any of their values changed. old and cur may be
+// nil.
+func Diff(old, cur *Config) []Change {
+	var changes []Change
+	if old == nil {
+		old = newConfig()
+	}
+	if cur == nil {
+		cur = newConfig()
+	}
+	for section, values := range old.Sections {
+		for key, value := range values {
+			sk := sectionKey{section, key}
+			if now, ok := cur.Sections[section][key]; !ok {
+				changes = append(changes, Change{section, key, KeyRemoved, value, ""})
+			} else if now != value || !sameValues(old.multi[sk], cur.multi[sk]) {
+				changes = append(changes, Change{section, key, KeyModified, value, now})
+			}
+		}
+	}
+	for section, values := range cur.Sections {
+		for key, value := range values {
+			if _, ok := old.Sections[section][key]; !ok {
+				changes = append(changes, Change{section, key, KeyAdded, "", value})
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Section != changes[j].Section {
+			return changes[i].Section < changes[j].Section
+		}
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// sameValues checks, if the values of two repeated keys are equal.
+func sameValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+// ChangeFunc is notified by a Watcher about a reloaded configuration.
+type ChangeFunc func(cfg *Config, changes []Change)
+
+// fileState is the last known state of a watched file.
+type fileState struct {
+	modTime time.Time
+	size    int64
+	sum     [sha256.Size]byte
+}
+
+// Watcher reloads a configuration file, if it or one of its included files
+// changes. The files are polled for changes of their modification time and
+// size, which are verified by a checksum of the contents. Include patterns
+// with wildcards and drop-in directories are polled as well, so that files
+// added or removed later on cause a reload.
+//
+// A reloaded configuration is checked by the Validator and only replaces the
+// current one, if it is valid. The Config instances provided by the Watcher
+// are swapped atomically and may be read concurrently, but must not be
+// modified.
+type Watcher struct {
+	// OnError is called, if a reload fails. If it is nil, the errors are
+	// ignored and the current configuration is kept.
+	OnError func(err error)
+
+	filename string
+	loader   func() (*Config, error)
+	interval time.Duration
+	current  atomic.Value
+
+	mux         sync.Mutex
+	files       map[string]fileState
+	globs       map[string]string
+	subscribers []ChangeFunc
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+// NewWatcher creates a new Watcher for the passed file, which is polled in
+// the passed interval after Start was called. The file is loaded and
+// validated initially.
+func NewWatcher(filename string, interval time.Duration, validate Validator) (*Watcher, error) {
+	return newWatcher(filename, interval, func() (*Config, error) {
+		return LoadFile(filename, validate)
+	})
+}
+
+// NewDropInWatcher creates a new Watcher for the passed file and drop-in
+// directory, which are loaded via LoadDropIn. Files added to or removed from
+// the drop-in directory cause a reload.
+func NewDropInWatcher(filename, dir string, interval time.Duration, validate Validator) (*Watcher, error) {
+	return newWatcher(filename, interval, func() (*Config, error) {
+		return LoadDropIn(filename, dir, validate)
+	})
+}
+
+func newWatcher(filename string, interval time.Duration, loader func() (*Config, error)) (*Watcher, error) {
+	w := &Watcher{
+		filename: filename,
+		loader:   loader,
+		interval: interval,
+	}
+	cfg, files, err := w.load()
+	if err != nil {
+		return nil, err
+	}
+	w.files = files
+	w.globs = globsOf(cfg)
+	w.current.Store(cfg)
+	return w, nil
+}
+
+// Config gets the current configuration.
+func (w *Watcher) Config() *Config {
+	return w.current.Load().(*Config)
+}
+
+// Subscribe registers a function, which is called with the new configuration
+// and the changed keys, whenever a changed configuration was loaded.
+func (w *Watcher) Subscribe(fn ChangeFunc) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	w.subscribers = append(w.subscribers, fn)
+}
+
+// Start starts polling the files in the background. Calling Start on a
+// running Watcher is a no-op.
+func (w *Watcher) Start() {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if w.stop != nil {
+		return
+	}
+	w.stop = make(chan struct{})
+	w.done = make(chan struct{})
+	go w.run(w.stop, w.done)
+}
+
+// Stop stops polling the files and waits for a running reload to finish.
+func (w *Watcher) Stop() {
+	w.mux.Lock()
+	stop, done := w.stop, w.done
+	w.stop, w.done = nil, nil
+	w.mux.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+func (w *Watcher) run(stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := w.Reload(); err != nil && w.OnError != nil {
+				w.OnError(err)
+			}
+		}
+	}
+}
+
+// Reload checks the files for changes and reloads the configuration, if
+// necessary. It returns the changed keys. If the configuration cannot be
+// loaded or is invalid, the current configuration is kept and the error is
+// returned.
+func (w *Watcher) Reload() ([]Change, error) {
+	w.mux.Lock()
+	if !w.modified() {
+		w.mux.Unlock()
+		return nil, nil
+	}
+	cfg, files, err := w.load()
+	if err != nil {
+		// Keep the state of the broken files to report the error only once
+		// per change.
+		for fname := range w.files {
+			w.files[fname], _ = stateOf(fname)
+		}
+		for pattern := range w.globs {
+			w.globs[pattern] = glob(pattern)
+		}
+		w.mux.Unlock()
+		return nil, err
+	}
+	w.files = files
+	w.globs = globsOf(cfg)
+	changes := Diff(w.Config(), cfg)
+	if len(changes) == 0 {
+		w.mux.Unlock()
+		return nil, nil
+	}
+	w.current.Store(cfg)
+	subscribers := w.subscribers
+	w.mux.Unlock()
+
+	for _, fn := range subscribers {
+		fn(cfg, changes)
+	}
+	return changes, nil
+}
+
+// modified checks, if any of the watched files changed or if files were
+// added to or removed from the matches of the watched patterns.
+func (w *Watcher) modified() bool {
+	for pattern, matches := range w.globs {
+		if glob(pattern) != matches {
+			return true
+		}
+	}
+	for fname, state := range w.files {
+		info, err := os.Stat(fname)
+		if err != nil {
+			// Missing files are only modified, if they existed before.
+			if state.modTime.IsZero() {
+				continue
+			}
+			return true
+		}
+		if info.ModTime().Equal(state.modTime) && info.Size() == state.size {
+			continue
+		}
+		data, err := ioutil.ReadFile(fname)
+		if err != nil || sha256.Sum256(data) != state.sum {
+			return true
+		}
+		// Only touched, keep the new state for the next check.
+		state.modTime, state.size = info.ModTime(), info.Size()
+		w.files[fname] = state
+	}
+	return false
+}
+
+// load loads and validates the configuration and gets the state of the file
+// and its included files.
+func (w *Watcher) load() (*Config, map[string]fileState, error) {
+	cfg, err := w.loader()
+	if err != nil {
+		return nil, nil, err
+	}
+	files := make(map[string]fileState)
+	names := []string{w.filename}
+	for _, l := range cfg.lines {
+		if l.file != "" {
+			names = append(names, l.file)
+		}
+	}
+	for _, fname := range names {
+		if _, ok := files[fname]; ok {
+			continue
+		}
+		if files[fname], err = stateOf(fname); err != nil {
+			return nil, nil, err
+		}
+	}
+	return cfg, files, nil
+}
+
+// globsOf gets the current matches of the include patterns of the Config.
+func globsOf(cfg *Config) map[string]string {
+	globs := make(map[string]string)
+	for _, pattern := range cfg.patterns {
+		globs[pattern] = glob(pattern)
+	}
+	return globs
+}
+
+// glob gets the files matching the pattern as a single string.
+func glob(pattern string) string {
+	files, _ := filepath.Glob(pattern)
+	return strings.Join(files, "\n")
+}
+
+// stateOf gets the current state of a file.
+func stateOf(fname string) (fileState, error) {
+	info, err := os.Stat(fname)
+	if err != nil {
+		return fileState{}, err
+	}
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return fileState{}, err
+	}
+	return fileState{info.ModTime(), info.Size(), sha256.Sum256(data)}, nil
+}
@@ -0,0 +1,225 @@
+package config_test
+
+import (
+	"errors"
+	"github.com/marcusva/gadget/config"
+	"github.com/marcusva/gadget/testing/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	old, err := config.Load(strings.NewReader("[a]\nx = 1\ny = 2\n[b]\nz = 3\n"), nil)
+	assert.FailOnErr(t, err)
+	cur, err := config.Load(strings.NewReader("[a]\nx = 1\ny = 5\n[c]\nw = 4\n"), nil)
+	assert.FailOnErr(t, err)
+
+	changes := config.Diff(old, cur)
+	assert.Equal(t, len(changes), 3)
+	assert.Equal(t, changes[0], config.Change{Section: "a", Key: "y", Kind: config.KeyModified, Old: "2", New: "5"})
+	assert.Equal(t, changes[1], config.Change{Section: "b", Key: "z", Kind: config.KeyRemoved, Old: "3"})
+	assert.Equal(t, changes[2], config.Change{Section: "c", Key: "w", Kind: config.KeyAdded, New: "4"})
+	assert.Equal(t, changes[2].Kind.String(), "added")
+
+	assert.Equal(t, len(config.Diff(old, old)), 0)
+	assert.Equal(t, len(config.Diff(nil, cur)), 3)
+
+	// Changed values of repeated keys
+	old, err = config.Load(strings.NewReader("[s]\nv[] = 1\nv[] = 2\n"), nil)
+	assert.FailOnErr(t, err)
+	cur, err = config.Load(strings.NewReader("[s]\nv[] = 9\nv[] = 2\n"), nil)
+	assert.FailOnErr(t, err)
+	changes = config.Diff(old, cur)
+	assert.Equal(t, len(changes), 1)
+	assert.Equal(t, changes[0], config.Change{Section: "s", Key: "v", Kind: config.KeyModified, Old: "2", New: "2"})
+}
+
+func TestWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "gadget-cfgtest")
+	assert.FailOnErr(t, err)
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "test.ini")
+	sub := filepath.Join(dir, "sub.ini")
+	writeFiles(t, dir, map[string]string{
+		"test.ini": "[log]\nlevel = Debug\n!include sub.ini\n",
+		"sub.ini":  "[db]\nhost = localhost\n",
+	})
+	validate := func(cfg *config.Config) error {
+		if _, err := cfg.Get("log", "level"); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	w, err := config.NewWatcher(fname, time.Millisecond, validate)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, w.Config().GetOrPanic("log", "level"), "Debug")
+
+	var notified [][]config.Change
+	w.Subscribe(func(cfg *config.Config, changes []config.Change) {
+		notified = append(notified, changes)
+	})
+
+	// Unchanged files
+	changes, err := w.Reload()
+	assert.FailOnErr(t, err)
+	assert.Equal(t, len(changes), 0)
+
+	// Changed included file
+	assert.FailOnErr(t, ioutil.WriteFile(sub, []byte("[db]\nhost = db.example.com\n"), 0644))
+	changes, err = w.Reload()
+	assert.FailOnErr(t, err)
+	assert.Equal(t, len(changes), 1)
+	assert.Equal(t, changes[0].New, "db.example.com")
+	assert.Equal(t, w.Config().GetOrPanic("db", "host"), "db.example.com")
+	assert.Equal(t, len(notified), 1)
+
+	// Invalid configurations are reported once and not applied.
+	assert.FailOnErr(t, ioutil.WriteFile(fname, []byte("[log]\n!include sub.ini\n"), 0644))
+	_, err = w.Reload()
+	assert.Err(t, err)
+	_, err = w.Reload()
+	assert.FailOnErr(t, err)
+	assert.Equal(t, w.Config().GetOrPanic("log", "level"), "Debug")
+	assert.Equal(t, len(notified), 1)
+
+	_, err = config.NewWatcher(filepath.Join(dir, "missing.ini"), time.Second, nil)
+	assert.Err(t, err)
+}
+
+func TestWatcherList(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "gadget-cfgtest")
+	assert.FailOnErr(t, err)
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "test.ini")
+	writeFiles(t, dir, map[string]string{"test.ini": "[s]\nv[] = 1\nv[] = 2\n"})
+	w, err := config.NewWatcher(fname, time.Second, nil)
+	assert.FailOnErr(t, err)
+
+	writeFiles(t, dir, map[string]string{"test.ini": "[s]\nv[] = 9\nv[] = 2\n"})
+	changes, err := w.Reload()
+	assert.FailOnErr(t, err)
+	assert.Equal(t, len(changes), 1)
+	values, err := w.Config().List("s", "v")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, values, []string{"9", "2"})
+}
+
+func TestWatcherPattern(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "gadget-cfgtest")
+	assert.FailOnErr(t, err)
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "test.ini")
+	writeFiles(t, dir, map[string]string{
+		"test.ini": "[log]\nlevel = Debug\n!include conf.d/*.ini\n",
+	})
+	w, err := config.NewWatcher(fname, time.Second, nil)
+	assert.FailOnErr(t, err)
+
+	// New file matching the pattern
+	writeFiles(t, dir, map[string]string{"conf.d/db.ini": "[db]\nhost = localhost\n"})
+	changes, err := w.Reload()
+	assert.FailOnErr(t, err)
+	assert.Equal(t, len(changes), 1)
+	assert.Equal(t, w.Config().GetOrPanic("db", "host"), "localhost")
+
+	// Removed file
+	assert.FailOnErr(t, os.Remove(filepath.Join(dir, "conf.d", "db.ini")))
+	changes, err = w.Reload()
+	assert.FailOnErr(t, err)
+	assert.Equal(t, len(changes), 1)
+	assert.Equal(t, changes[0].Kind, config.KeyRemoved)
+	assert.Equal(t, w.Config().HasSection("db"), false)
+}
+
+func TestDropInWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "gadget-cfgtest")
+	assert.FailOnErr(t, err)
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "test.ini")
+	dropin := filepath.Join(dir, "test.d")
+	writeFiles(t, dir, map[string]string{"test.ini": "[log]\nlevel = Debug\n"})
+	w, err := config.NewDropInWatcher(fname, dropin, time.Second, nil)
+	assert.FailOnErr(t, err)
+
+	changes, err := w.Reload()
+	assert.FailOnErr(t, err)
+	assert.Equal(t, len(changes), 0)
+
+	// The drop-in directory is created later on.
+	writeFiles(t, dir, map[string]string{"test.d/10-log.ini": "[log]\nlevel = Info\n"})
+	changes, err = w.Reload()
+	assert.FailOnErr(t, err)
+	assert.Equal(t, len(changes), 1)
+	assert.Equal(t, w.Config().GetOrPanic("log", "level"), "Info")
+
+	// Changed drop-in file
+	writeFiles(t, dir, map[string]string{"test.d/10-log.ini": "[log]\nlevel = Error\n"})
+	changes, err = w.Reload()
+	assert.FailOnErr(t, err)
+	assert.Equal(t, len(changes), 1)
+	assert.Equal(t, w.Config().GetOrPanic("log", "level"), "Error")
+}
+
+func TestWatcherStart(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "gadget-cfgtest")
+	assert.FailOnErr(t, err)
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "test.ini")
+	assert.FailOnErr(t, ioutil.WriteFile(fname, []byte("[log]\nlevel = Debug\n"), 0644))
+	w, err := config.NewWatcher(fname, time.Millisecond, nil)
+	assert.FailOnErr(t, err)
+
+	reloaded := make(chan struct{})
+	w.Subscribe(func(cfg *config.Config, changes []config.Change) {
+		// The file may be read while being written.
+		if cfg.GetDefault("log", "level", "") == "Info" {
+			close(reloaded)
+		}
+	})
+	var errmux sync.Mutex
+	var errs []error
+	w.OnError = func(err error) {
+		errmux.Lock()
+		defer errmux.Unlock()
+		errs = append(errs, err)
+	}
+	w.Start()
+	w.Start()
+	defer w.Stop()
+
+	// Concurrent readers
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				w.Config().GetDefault("log", "level", "")
+			}
+		}()
+	}
+	assert.FailOnErr(t, ioutil.WriteFile(fname, []byte("[log]\nlevel = Info\n"), 0644))
+	select {
+	case <-reloaded:
+		assert.Equal(t, w.Config().GetOrPanic("log", "level"), "Info")
+	case <-time.After(5 * time.Second):
+		assert.FailOnErr(t, errors.New("configuration was not reloaded"))
+	}
+	wg.Wait()
+	w.Stop()
+	w.Stop()
+	errmux.Lock()
+	defer errmux.Unlock()
+	assert.Equal(t, len(errs), 0)
+}
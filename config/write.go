@@ -0,0 +1,386 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type lineKind int
+
+const (
+	lineBlank lineKind = iota
+	lineComment
+	lineSection
+	lineKey
+	lineInclude
+)
+
+// docLine is a single line of a loaded configuration file.
+type docLine struct {
+	kind    lineKind
+	raw     string
+	section string
+	key     string
+	value   string
+	// tail is the inline comment following the value.
+	tail string
+	// file, num and col are the name of the file, the line number within it
+	// and the column of the first character.
+	file string
+	num  int
+	col  int
+	// included is true for lines of included files, which are not written.
+	included bool
+	// ignored is true for key definitions, which were ignored due to the
+	// DuplicateFirst policy.
+	ignored bool
+}
+
+type sectionKey struct {
+	section string
+	key     string
+}
+
+// Set sets the value for a certain key within the specified section. If the
+// section does not exist, it will be created. Sections, keys and values,
+// which cannot be written, are rejected by WriteTo.
+func (cfg *Config) Set(section, key, value string) {
+	section, key = cfg.foldSection(section), cfg.fold(key)
+	delete(cfg.multi, sectionKey{section, key})
+	if cfg.Sections == nil {
+		cfg.Sections = make(map[string]map[string]string)
+	}
+	opts, ok := cfg.Sections[section]
+	if !ok {
+		opts = make(map[string]string)
+		cfg.Sections[section] = opts
+	}
+	if _, ok := opts[key]; !ok {
+		cfg.added = append(cfg.added, sectionKey{section, key})
+	}
+	opts[key] = value
+}
+
+// Remove removes a key from the specified section. If the section or key
+// do not exist, this is a no-op.
+func (cfg *Config) Remove(section, key string) {
+	section, key = cfg.foldSection(section), cfg.fold(key)
+	delete(cfg.multi, sectionKey{section, key})
+	delete(cfg.lists, sectionKey{section, key})
+	if opts, ok := cfg.Sections[section]; ok {
+		delete(opts, key)
+	}
+}
+
+// RemoveSection removes the specified section with all of its keys. If the
+// section does not exist, this is a no-op.
+func (cfg *Config) RemoveSection(section string) {
+	section = cfg.foldSection(section)
+	for sk := range cfg.multi {
+		if sk.section == section {
+			delete(cfg.multi, sk)
+		}
+	}
+	for sk := range cfg.lists {
+		if sk.section == section {
+			delete(cfg.lists, sk)
+		}
+	}
+	delete(cfg.subsections, section)
+	delete(cfg.Sections, section)
+}
+
+// valueLine replaces the value of a key-value line, keeping the formatting of
+// the key and assignment.
+func valueLine(raw, value string) string {
+	eq := strings.IndexByte(raw, '=')
+	after := raw[eq+1:]
+	trimmed := strings.TrimLeft(after, " \t")
+	if strings.TrimSpace(trimmed) == "" {
+		// No value to take the spacing from, use the spacing of the key.
+		if eq > 0 && (raw[eq-1] == ' ' || raw[eq-1] == '\t') {
+			return raw[:eq+1] + " " + value
+		}
+		return raw[:eq+1] + value
+	}
+	return raw[:eq+1+len(after)-len(trimmed)] + value
+}
+
+// checkSection checks, if a section name can be written as section header,
+// so that it is read back as is.
+func checkSection(section string) error {
+	switch {
+	case section == "":
+		return errors.New("empty section name")
+	case strings.ContainsAny(section, "\n\r\""):
+		return errors.New("section name must not contain line breaks or quotes")
+	case section != strings.TrimSpace(section):
+		return errors.New("section name must not have surrounding whitespace")
+	}
+	return nil
+}
+
+// checkKey checks, if a key can be written, so that it is read back as is.
+func checkKey(key string) error {
+	switch {
+	case key == "":
+		return errors.New("empty key")
+	case strings.ContainsAny(key, "=\n\r"):
+		return errors.New("key must not contain '=' or line breaks")
+	case key != strings.TrimSpace(key):
+		return errors.New("key must not have surrounding whitespace")
+	case key[0] == '#', key[0] == ';', key[0] == '[':
+		return errors.New("key must not start with '#', ';' or '['")
+	case strings.HasSuffix(key, "[]"), isInclude(key + " "):
+		return errors.New("key must not end with '[]' or be an include directive")
+	}
+	return nil
+}
+
+// newKeys returns the keys of the section, which are not part of the loaded
+// lines. Keys added via Set keep their order, all others are sorted.
+func (cfg *Config) newKeys(section string, known map[sectionKey]bool) []string {
+	var keys, unordered []string
+	done := make(map[string]bool)
+	for _, sk := range cfg.added {
+		if sk.section != section || known[sk] || done[sk.key] {
+			continue
+		}
+		if _, ok := cfg.Sections[section][sk.key]; ok {
+			keys = append(keys, sk.key)
+			done[sk.key] = true
+		}
+	}
+	for key := range cfg.Sections[section] {
+		if !known[sectionKey{section, key}] && !done[key] {
+			unordered = append(unordered, key)
+		}
+	}
+	sort.Strings(unordered)
+	return append(keys, unordered...)
+}
+
+// WriteTo writes the Config in the INI file format to w. The comments,
+// blank lines, ordering and formatting of a loaded configuration are kept for
+// all sections and keys, which were not changed. Changed values are written
+// in place, keys added are written after the last key of their section and
+// sections added are written at the end.
+//
+// WriteTo fails without writing anything, if a section, key or value cannot
+// be written, so that it is read back as is, e.g. a key containing a '=' or a
+// value with line breaks, which requires the Quotes or Heredoc option.
+//
+// Lines of included files are not written. Keys, whose definition in effect
+// is part of an included file, cannot be changed or removed, since the
+// included file would override the main configuration again on loading it.
+// WriteTo fails for such keys.
+func (cfg *Config) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var written int64
+	blank := true
+	writeLine := func(text string) {
+		blank = strings.TrimSpace(text) == ""
+		n, _ := bw.WriteString(text)
+		written += int64(n)
+		n, _ = bw.WriteString("\n")
+		written += int64(n)
+	}
+	// formatted contains the formatted values of all added and changed keys.
+	formatted := make(map[sectionKey]string)
+	writeKeys := func(section string, keys []string) {
+		for _, key := range keys {
+			writeLine(key + " = " + formatted[sectionKey{section, key}])
+		}
+	}
+
+	// effective contains the line of the definition in effect for each key,
+	// local the last line of each key, which is not part of an included file.
+	effective := make(map[sectionKey]int)
+	local := make(map[sectionKey]int)
+	// defs contains the amount of definitions in effect for each key.
+	defs := make(map[sectionKey]int)
+	sections := make(map[string]bool)
+	included := make(map[string]bool)
+	for idx, l := range cfg.lines {
+		sk := sectionKey{l.section, l.key}
+		switch {
+		case l.kind == lineSection && l.included:
+			included[l.section] = true
+		case l.kind == lineSection:
+			sections[l.section] = true
+		case l.kind == lineKey && !l.ignored:
+			effective[sk] = idx
+			defs[sk]++
+			if !l.included {
+				local[sk] = idx
+				// Keys of the GlobalSection have no section line.
+				sections[l.section] = true
+			}
+		}
+	}
+	changed := func(sk sectionKey) bool {
+		if _, ok := cfg.multi[sk]; !ok && defs[sk] > 1 && cfg.accumulates(sk) {
+			// The accumulated values were replaced.
+			return true
+		}
+		return cfg.Sections[sk.section][sk.key] != cfg.lines[effective[sk]].value
+	}
+	known := make(map[sectionKey]bool)
+	for sk, idx := range effective {
+		known[sk] = true
+		l := cfg.lines[idx]
+		if !l.included {
+			continue
+		}
+		if _, ok := cfg.Sections[sk.section][sk.key]; !ok {
+			return 0, fmt.Errorf("section '%s', key '%s': key of the included file '%s' cannot be removed",
+				sk.section, sk.key, l.file)
+		}
+		if changed(sk) {
+			return 0, fmt.Errorf("section '%s', key '%s': key of the included file '%s' cannot be changed",
+				sk.section, sk.key, l.file)
+		}
+	}
+
+	// Check and format everything, which is not written as loaded.
+	for section, values := range cfg.Sections {
+		if !sections[section] && !included[section] {
+			if err := checkSection(section); err != nil {
+				return 0, fmt.Errorf("section '%s': %v", section, err)
+			}
+		}
+		for key, value := range values {
+			sk := sectionKey{section, key}
+			if known[sk] && !changed(sk) {
+				continue
+			}
+			if !known[sk] {
+				if err := checkKey(key); err != nil {
+					return 0, fmt.Errorf("section '%s', key '%s': %v", section, key, err)
+				}
+			}
+			value, err := cfg.formatValue(value)
+			if err != nil {
+				return 0, fmt.Errorf("section '%s', key '%s': %v", section, key, err)
+			}
+			formatted[sk] = value
+		}
+	}
+
+	// Find the last line of each section, after which added keys will be
+	// written.
+	last := make(map[string]int)
+	for idx, l := range cfg.lines {
+		if !l.included && (l.kind == lineSection || l.kind == lineKey) {
+			last[l.section] = idx
+		}
+	}
+
+	for idx, l := range cfg.lines {
+		if l.included {
+			continue
+		}
+		if l.section != "" {
+			if _, ok := cfg.Sections[l.section]; !ok {
+				// The section was removed
+				continue
+			}
+		}
+		switch l.kind {
+		case lineKey:
+			sk := sectionKey{l.section, l.key}
+			_, ok := cfg.Sections[l.section][l.key]
+			switch {
+			case !ok:
+				// The key was removed
+			case l.ignored || !changed(sk):
+				writeLine(l.raw)
+			case local[sk] == idx:
+				raw := strings.TrimRight(l.raw, " \t")
+				raw = raw[:len(raw)-len(l.tail)]
+				if strings.ContainsRune(formatted[sk], '\n') {
+					// Comments cannot follow multi-line values.
+					writeLine(valueLine(raw, formatted[sk]))
+				} else {
+					writeLine(valueLine(raw, formatted[sk]) + l.tail)
+				}
+			case cfg.accumulates(sk):
+				// Only the changed value is written.
+			default:
+				writeLine(l.raw)
+			}
+		default:
+			writeLine(l.raw)
+		}
+		if l.section != "" && last[l.section] == idx {
+			writeKeys(l.section, cfg.newKeys(l.section, known))
+		}
+	}
+
+	// Write the sections, which are not part of the loaded lines. Sections
+	// added via Set keep their order, all others are sorted.
+	var added, unordered []string
+	for _, sk := range cfg.added {
+		if _, ok := cfg.Sections[sk.section]; ok && !sections[sk.section] {
+			added = append(added, sk.section)
+			sections[sk.section] = true
+		}
+	}
+	for section := range cfg.Sections {
+		if !sections[section] {
+			unordered = append(unordered, section)
+		}
+	}
+	sort.Strings(unordered)
+	for _, section := range append(added, unordered...) {
+		keys := cfg.newKeys(section, known)
+		if included[section] && len(keys) == 0 {
+			continue
+		}
+		if !blank {
+			writeLine("")
+		}
+		writeLine("[" + section + "]")
+		writeKeys(section, keys)
+	}
+	return written, bw.Flush()
+}
+
+// SaveFile writes the Config to the passed file. The file is replaced
+// atomically by writing to a temporary file first, which is renamed
+// afterwards. The permissions of an existing file are kept, new files are
+// created with the permission 0644.
+func (cfg *Config) SaveFile(filename string) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(filename); err == nil {
+		mode = info.Mode().Perm()
+	}
+	fp, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename))
+	if err != nil {
+		return err
+	}
+	tmpname := fp.Name()
+	if _, err = cfg.WriteTo(fp); err == nil {
+		err = fp.Sync()
+	}
+	if cerr := fp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmpname, mode)
+	}
+	if err == nil {
+		err = os.Rename(tmpname, filename)
+	}
+	if err != nil {
+		os.Remove(tmpname)
+	}
+	return err
+}
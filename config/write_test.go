@@ -0,0 +1,165 @@
+package config_test
+
+import (
+	"bytes"
+	"github.com/marcusva/gadget/config"
+	"github.com/marcusva/gadget/testing/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var _handwritten = `# Global settings
+; generated by hand
+
+[server]
+host   = localhost   
+port=8080
+  # the admin user
+admin =
+
+[log]
+level = Debug
+# file = /var/log/app.log
+
+[obsolete]
+key = value
+`
+
+func TestWriteTo(t *testing.T) {
+	cfg, err := config.Load(strings.NewReader(_handwritten), config.NoValidate)
+	assert.FailOnErr(t, err)
+
+	var buf bytes.Buffer
+	n, err := cfg.WriteTo(&buf)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, int(n), buf.Len())
+	assert.Equal(t, buf.String(), _handwritten)
+
+	cfg.Set("server", "port", "9090")
+	cfg.Set("server", "admin", "root")
+	cfg.Set("server", "timeout", "30s")
+	cfg.Set("log", "file", "/tmp/app.log")
+	cfg.Remove("log", "level")
+	cfg.RemoveSection("obsolete")
+	cfg.Set("db", "host", "db01")
+	cfg.Set("db", "user", "app")
+
+	buf.Reset()
+	_, err = cfg.WriteTo(&buf)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, buf.String(), `# Global settings
+; generated by hand
+
+[server]
+host   = localhost   
+port=9090
+  # the admin user
+admin = root
+timeout = 30s
+
+[log]
+file = /tmp/app.log
+# file = /var/log/app.log
+
+[db]
+host = db01
+user = app
+`)
+
+	// The written configuration can be loaded again
+	cfg2, err := config.Load(&buf, config.NoValidate)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, cfg2.Sections, cfg.Sections)
+}
+
+func TestWriteToNew(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Set("b", "key", "value")
+	cfg.Set("a", "z", "1")
+	cfg.Set("a", "y", "2")
+	cfg.Sections["c"] = map[string]string{"k2": "v2", "k1": "v1"}
+
+	var buf bytes.Buffer
+	_, err := cfg.WriteTo(&buf)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, buf.String(), `[b]
+key = value
+
+[a]
+z = 1
+y = 2
+
+[c]
+k1 = v1
+k2 = v2
+`)
+}
+
+func TestWriteToInvalid(t *testing.T) {
+	invalid := []struct {
+		section, key, value string
+	}{
+		{"a", "k", "line1\nline2"},
+		{"a", "k", "carriage\rreturn"},
+		{"a", "k", " spaced "},
+		{"a", "x=y", "z"},
+		{"a", "", "z"},
+		{"a", " k", "z"},
+		{"a", "#k", "z"},
+		{"a", "k[]", "z"},
+		{"a", "!include", "z"},
+		{"", "k", "z"},
+		{"a\nb", "k", "z"},
+		{"a \"b\"", "k", "z"},
+	}
+	for _, entry := range invalid {
+		cfg, err := config.Load(strings.NewReader("[a]\nkey = value\n"), nil)
+		assert.FailOnErr(t, err)
+		cfg.Set(entry.section, entry.key, entry.value)
+		var buf bytes.Buffer
+		_, err = cfg.WriteTo(&buf)
+		assert.Err(t, err, "%q, %q, %q was written", entry.section, entry.key, entry.value)
+		assert.Equal(t, buf.Len(), 0)
+	}
+
+	// Values, which can be written, are read back as is
+	cfg := &config.Config{}
+	cfg.Set("a", "k", "a # b ; c \\")
+	cfg.Set("a", "x", "")
+	cfg.Set("a", "y", "\"quoted\"")
+	var buf bytes.Buffer
+	_, err := cfg.WriteTo(&buf)
+	assert.FailOnErr(t, err)
+	reloaded, err := config.Load(strings.NewReader(buf.String()), nil)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, len(config.Diff(cfg, reloaded)), 0)
+}
+
+func TestSaveFile(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "gadget-cfgtest")
+	assert.FailOnErr(t, err)
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "test.ini")
+	assert.FailOnErr(t, ioutil.WriteFile(fname, []byte(_handwritten), 0600))
+	cfg, err := config.LoadFile(fname, config.NoValidate)
+	assert.FailOnErr(t, err)
+	cfg.Set("log", "level", "Info")
+	assert.FailOnErr(t, cfg.SaveFile(fname))
+
+	data, err := ioutil.ReadFile(fname)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, string(data), strings.Replace(_handwritten, "level = Debug", "level = Info", 1))
+	info, err := os.Stat(fname)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0600))
+
+	files, err := ioutil.ReadDir(dir)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, len(files), 1)
+
+	assert.Err(t, cfg.SaveFile(filepath.Join(dir, "missing", "test.ini")))
+}
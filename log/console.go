@@ -0,0 +1,95 @@
+package log
+
+import (
+	"io"
+	"os"
+)
+
+const (
+	colorReset = "\x1b[0m"
+	colorField = "\x1b[36m"
+)
+
+// ColorMode controls the colored output of the ConsoleFormatter.
+type ColorMode int
+
+const (
+	// ColorAuto enables colors, if the output is a terminal and the NO_COLOR
+	// environment variable is not set.
+	ColorAuto ColorMode = iota
+	// ColorAlways enables colors unconditionally.
+	ColorAlways
+	// ColorNever disables colors.
+	ColorNever
+)
+
+// levelColors are the ANSI escape sequences used for the individual levels.
+var levelColors = []string{
+	"\x1b[1;41;37m", // EMERGENCY
+	"\x1b[1;35m",    // ALERT
+	"\x1b[1;31m",    // CRITICAL
+	"\x1b[31m",      // ERROR
+	"\x1b[33m",      // WARNING
+	"\x1b[34m",      // NOTICE
+	"\x1b[32m",      // INFO
+	"\x1b[2m",       // DEBUG
+}
+
+// palette provides the escape sequences for a colored output.
+type palette struct {
+	fields bool
+}
+
+func (p *palette) level(level Level) string {
+	if level < LevelEmergency || level > LevelDebug {
+		return colorReset
+	}
+	return levelColors[level]
+}
+
+// ConsoleFormatter writes records in the same format as the TextFormatter,
+// but colors the levels and, optionally, the keys of the fields.
+type ConsoleFormatter struct {
+	TextFormatter
+	// Color is true, if colored output shall be written.
+	Color bool
+	// HighlightFields enables the coloring of field keys.
+	HighlightFields bool
+}
+
+// NewConsoleFormatter creates a new ConsoleFormatter for the passed output.
+// With ColorAuto, colors are only enabled, if out is a terminal and the
+// NO_COLOR environment variable is not set.
+func NewConsoleFormatter(out io.Writer, mode ColorMode) *ConsoleFormatter {
+	color := false
+	switch mode {
+	case ColorAlways:
+		color = true
+	case ColorAuto:
+		color = os.Getenv("NO_COLOR") == "" && IsTerminal(out)
+	}
+	return &ConsoleFormatter{Color: color, HighlightFields: true}
+}
+
+// Format formats the passed Record, including the trailing newline.
+func (f *ConsoleFormatter) Format(rec *Record) []byte {
+	if !f.Color {
+		return f.TextFormatter.Format(rec)
+	}
+	colors := &palette{fields: f.HighlightFields}
+	return append(f.TextFormatter.format(rec, colors), '\n')
+}
+
+// IsTerminal checks, if the passed writer is a terminal. This is the case
+// for *os.File values referring to a character device, such as the console.
+func IsTerminal(out io.Writer) bool {
+	fp, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := fp.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
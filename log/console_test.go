@@ -0,0 +1,65 @@
+package log_test
+
+import (
+	"bytes"
+	"github.com/marcusva/gadget/log"
+	"github.com/marcusva/gadget/testing/assert"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsoleFormatter(t *testing.T) {
+	rec := &log.Record{
+		Time:    time.Now(),
+		Level:   log.LevelError,
+		Message: "failed",
+		Fields:  []log.Field{log.F("code", 7)},
+	}
+	var buf bytes.Buffer
+
+	f := log.NewConsoleFormatter(&buf, log.ColorAlways)
+	out := string(f.Format(rec))
+	assert.FailIfNot(t, strings.Contains(out, "\x1b[31mERROR    \x1b[0m"),
+		"level not colored: %q", out)
+	assert.FailIfNot(t, strings.Contains(out, "\x1b[36mcode\x1b[0m=7"),
+		"field not colored: %q", out)
+
+	f.HighlightFields = false
+	out = string(f.Format(rec))
+	assert.FailIfNot(t, strings.HasSuffix(out, "failed code=7\n"),
+		"field colored: %q", out)
+
+	f = log.NewConsoleFormatter(&buf, log.ColorNever)
+	assert.Equal(t, string(f.Format(rec)), string(log.TextFormatter{}.Format(rec)))
+
+	// A buffer is never a terminal
+	f = log.NewConsoleFormatter(&buf, log.ColorAuto)
+	assert.Equal(t, f.Color, false)
+}
+
+func TestConsoleFormatterNoColor(t *testing.T) {
+	old, isset := os.LookupEnv("NO_COLOR")
+	defer func() {
+		if isset {
+			os.Setenv("NO_COLOR", old)
+		} else {
+			os.Unsetenv("NO_COLOR")
+		}
+	}()
+	os.Setenv("NO_COLOR", "1")
+	assert.Equal(t, log.NewConsoleFormatter(os.Stdout, log.ColorAuto).Color, false)
+	// Forced colors ignore NO_COLOR
+	assert.Equal(t, log.NewConsoleFormatter(os.Stdout, log.ColorAlways).Color, true)
+}
+
+func TestIsTerminal(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Equal(t, log.IsTerminal(&buf), false)
+
+	fp, err := os.Open("console_test.go")
+	assert.FailOnErr(t, err)
+	defer fp.Close()
+	assert.Equal(t, log.IsTerminal(fp), false)
+}
@@ -0,0 +1,101 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrorFielder can be implemented by errors to add structured fields to the
+// record, if the error is logged via Err.
+type ErrorFielder interface {
+	LogFields() []Field
+}
+
+// Stacker can be implemented by errors, which captured the stack trace of
+// their creation, e.g. via runtime/debug.Stack().
+type Stacker interface {
+	Stack() []byte
+}
+
+// errorValue marks the value of a Field created by Err.
+type errorValue struct {
+	err error
+}
+
+func (e errorValue) String() string {
+	if e.err == nil {
+		return "<nil>"
+	}
+	return e.err.Error()
+}
+
+// Err creates a Field for the passed error. Besides the error message as
+// "error" field, the following fields will be added to the record:
+//
+//	error.type    the concrete type of err
+//	error.causes  the concrete types and messages of all errors wrapped by
+//	              err, if any
+//	error.stack   the stack trace of the first error in the chain, which
+//	              implements Stacker
+//
+// Wrapped errors are found via Unwrap() error, as used by fmt.Errorf("%w")
+// and Unwrap() []error, as used by errors.Join. All errors in the chain
+// implementing ErrorFielder add their fields to the record, too.
+func Err(err error) Field {
+	return Field{Key: "error", Value: errorValue{err}}
+}
+
+// unwrap gets the errors directly wrapped by err.
+func unwrap(err error) []error {
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		if inner := e.Unwrap(); inner != nil {
+			return []error{inner}
+		}
+	case interface{ Unwrap() []error }:
+		return e.Unwrap()
+	}
+	return nil
+}
+
+// errorChain returns err and all errors wrapped by it in depth-first order.
+func errorChain(err error) []error {
+	chain := []error{err}
+	for _, inner := range unwrap(err) {
+		if inner != nil {
+			chain = append(chain, errorChain(inner)...)
+		}
+	}
+	return chain
+}
+
+// errorFields expands a Field created by Err into the individual fields.
+func errorFields(key string, err error) []Field {
+	if err == nil {
+		return []Field{{Key: key, Value: "<nil>"}}
+	}
+	chain := errorChain(err)
+	fields := []Field{
+		{Key: key, Value: err.Error()},
+		{Key: key + ".type", Value: fmt.Sprintf("%T", err)},
+	}
+	if len(chain) > 1 {
+		causes := make([]string, len(chain)-1)
+		for idx, inner := range chain[1:] {
+			causes[idx] = fmt.Sprintf("%T: %s", inner, inner.Error())
+		}
+		fields = append(fields, Field{Key: key + ".causes", Value: strings.Join(causes, "; ")})
+	}
+	for _, inner := range chain {
+		if st, ok := inner.(Stacker); ok {
+			fields = append(fields, Field{Key: key + ".stack", Value: string(st.Stack())})
+			break
+		}
+	}
+	for _, inner := range chain {
+		if ef, ok := inner.(ErrorFielder); ok {
+			fields = append(fields, ef.LogFields()...)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,70 @@
+package log_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/marcusva/gadget/log"
+	"github.com/marcusva/gadget/testing/assert"
+	"strings"
+	"testing"
+)
+
+type stackError struct {
+	msg string
+}
+
+func (e *stackError) Error() string { return e.msg }
+func (e *stackError) Stack() []byte { return []byte("main.main()\n\tmain.go:12") }
+func (e *stackError) LogFields() []log.Field {
+	return []log.Field{log.F("query", "SELECT 1")}
+}
+
+type joinError struct {
+	errs []error
+}
+
+func (e *joinError) Error() string   { return "multiple errors" }
+func (e *joinError) Unwrap() []error { return e.errs }
+
+func logged(t *testing.T, fn func()) string {
+	var buf bytes.Buffer
+	log.Init(&buf, log.LevelDebug, false)
+	fn()
+	return strings.TrimSpace(buf.String())
+}
+
+func TestErr(t *testing.T) {
+	inner := &stackError{"connection reset"}
+	err := fmt.Errorf("query failed: %w", inner)
+
+	result := logged(t, func() { log.Error("request failed", log.Err(err)) })
+	expected := []string{
+		`error="query failed: connection reset"`,
+		`error.type=*fmt.wrapError`,
+		`error.causes="*log_test.stackError: connection reset"`,
+		`error.stack="main.main()\n\tmain.go:12"`,
+		`query="SELECT 1"`,
+	}
+	for _, e := range expected {
+		assert.FailIfNot(t, strings.Contains(result, e), "'%s' not found in %s", e, result)
+	}
+
+	err = &joinError{[]error{errors.New("first"), fmt.Errorf("second: %w", inner)}}
+	result = logged(t, func() { log.Errorf("%d errors", 2, log.Err(err)) })
+	expected = []string{
+		`2 errors error="multiple errors" error.type=*log_test.joinError`,
+		`error.causes="*errors.errorString: first; *fmt.wrapError: second: connection reset; *log_test.stackError: connection reset"`,
+	}
+	for _, e := range expected {
+		assert.FailIfNot(t, strings.Contains(result, e), "'%s' not found in %s", e, result)
+	}
+
+	result = logged(t, func() { log.Error("simple", log.Err(errors.New("failed"))) })
+	assert.FailIfNot(t, strings.HasSuffix(result, `[[simple]] error=failed error.type=*errors.errorString`),
+		"unexpected output: %s", result)
+
+	result = logged(t, func() { log.Error("nil", log.Err(nil)) })
+	assert.FailIfNot(t, strings.HasSuffix(result, `[[nil]] error=<nil>`),
+		"unexpected output: %s", result)
+}
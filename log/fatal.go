@@ -0,0 +1,134 @@
+package log
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+)
+
+var (
+	exitFunc      = os.Exit
+	exitCode      = 1
+	shutdownHooks []func()
+)
+
+// SetExitFunc sets the function, which is used by Fatal and Fatalf to exit
+// the program. This is os.Exit by default and can be replaced in tests.
+func SetExitFunc(fn func(code int)) {
+	mux.Lock()
+	defer mux.Unlock()
+	if fn == nil {
+		fn = os.Exit
+	}
+	exitFunc = fn
+}
+
+// SetExitCode sets the exit code used by Fatal and Fatalf. The default is 1.
+func SetExitCode(code int) {
+	mux.Lock()
+	defer mux.Unlock()
+	exitCode = code
+}
+
+// OnShutdown registers a function, which is invoked by Shutdown, Fatal and
+// Fatalf before the log output is closed. The functions are invoked in the
+// reverse order of their registration and may use the logging functions.
+func OnShutdown(fn func()) {
+	mux.Lock()
+	defer mux.Unlock()
+	shutdownHooks = append(shutdownHooks, fn)
+}
+
+// Shutdown runs the functions registered via OnShutdown, writes any buffered
+// output and closes the log file opened via InitFile or InitFileOptions.
+// Afterwards, all records are discarded until the logging functionality is
+// initialized again. Other log outputs passed to Init are flushed, but not
+// closed.
+func Shutdown() {
+	mux.Lock()
+	fns := shutdownHooks
+	shutdownHooks = nil
+	mux.Unlock()
+	for idx := len(fns) - 1; idx >= 0; idx-- {
+		fns[idx]()
+	}
+
+	defer reportErrors()
+	mux.Lock()
+	defer mux.Unlock()
+	flush(sink)
+	if logfile != nil {
+		logfile.Close()
+	}
+	logfile = nil
+	sink = ioutil.Discard
+	logger = log.New(sink, "", log.LstdFlags)
+}
+
+// Flush writes any output buffered by the log output, if the log output
+// provides a Flush() or Sync() method, such as bufio.Writer or os.File.
+func Flush() {
+	defer reportErrors()
+	mux.Lock()
+	defer mux.Unlock()
+	flush(sink)
+}
+
+// flush writes any output buffered by out. The lock has to be held.
+func flush(out interface{}) {
+	switch w := out.(type) {
+	case *FileSink:
+		failed(w, w.sync())
+	case interface{ Flush() error }:
+		w.Flush()
+	case interface{ Sync() error }:
+		w.Sync()
+	}
+}
+
+// exit shuts the logging down and exits the program.
+func exit() {
+	Shutdown()
+	mux.Lock()
+	fn, code := exitFunc, exitCode
+	mux.Unlock()
+	fn(code)
+}
+
+// Fatal writes an emergency message to the log, shuts the logging down via
+// Shutdown and exits the program with the exit code set via SetExitCode.
+func Fatal(args ...interface{}) {
+	_printval(LevelEmergency, args)
+	exit()
+}
+
+// Fatalf writes an emergency message to the log, shuts the logging down via
+// Shutdown and exits the program with the exit code set via SetExitCode.
+func Fatalf(format string, args ...interface{}) {
+	_printstr(LevelEmergency, format, args)
+	exit()
+}
+
+// Panic writes a critical message to the log, flushes the log output and
+// panics with the message. Since the panic may be recovered, neither the
+// functions registered via OnShutdown are invoked nor the log output is
+// closed.
+func Panic(args ...interface{}) {
+	values, fields := splitFields(resolve(args))
+	_printmsg(LevelCritical, 2, fmt.Sprintf("%v", []interface{}{values}), fields)
+	Flush()
+	panic(fmt.Sprint(values...))
+}
+
+// Panicf writes a critical message to the log, flushes the log output and
+// panics with the message. Since the panic may be recovered, neither the
+// functions registered via OnShutdown are invoked nor the log output is
+// closed.
+func Panicf(format string, args ...interface{}) {
+	values, fields := splitFields(resolve(args))
+	msg := fmt.Sprintf(format, values...)
+	_printmsg(LevelCritical, 2, msg, fields)
+	Flush()
+	panic(msg)
+}
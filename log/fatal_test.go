@@ -0,0 +1,114 @@
+package log_test
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/marcusva/gadget/log"
+	"github.com/marcusva/gadget/testing/assert"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFatal(t *testing.T) {
+	code := -1
+	log.SetExitFunc(func(c int) { code = c })
+	defer log.SetExitFunc(nil)
+
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	log.Init(out, log.LevelError, true)
+
+	var calls []string
+	log.OnShutdown(func() { calls = append(calls, "first") })
+	log.OnShutdown(func() {
+		calls = append(calls, "second")
+		log.Error("shutting down")
+	})
+
+	log.Fatalf("fatal %s", "error")
+	assert.Equal(t, code, 1)
+	assert.Equal(t, calls, []string{"second", "first"})
+	result := buf.String()
+	assert.FailIfNot(t, strings.Contains(result, "EMERGENCY [fatal_test.go:"),
+		"caller not found in %s", result)
+	assert.FailIfNot(t, strings.Contains(result, "fatal error\n"), "message not found in %s", result)
+	assert.FailIfNot(t, strings.Contains(result, "shutting down"), "message not found in %s", result)
+
+	// The output is discarded after shutting down
+	buf.Reset()
+	log.Emergency("discarded")
+	assert.Equal(t, buf.Len(), 0)
+
+	// Shutdown hooks are only run once
+	calls = nil
+	log.SetExitCode(3)
+	defer log.SetExitCode(1)
+	log.Init(&buf, log.LevelError, false)
+	log.Fatal("fatal")
+	assert.Equal(t, code, 3)
+	assert.Equal(t, len(calls), 0)
+	assert.FailIfNot(t, strings.Contains(buf.String(), "[[fatal]]"), "message not found in %s", buf.String())
+}
+
+func TestShutdownFile(t *testing.T) {
+	fp, err := ioutil.TempFile(os.TempDir(), "gadget-logtest")
+	assert.FailOnErr(t, err)
+	fname := fp.Name()
+	fp.Close()
+	defer os.Remove(fname)
+
+	assert.FailOnErr(t, log.InitFile(fname, log.LevelError, false))
+	log.Error("written")
+	log.Shutdown()
+	log.Error("discarded")
+
+	data, err := ioutil.ReadFile(fname)
+	assert.FailOnErr(t, err)
+	assert.FailIfNot(t, strings.HasSuffix(string(data), "[[written]]\n"), "unexpected content %s", data)
+
+	// Outputs passed to Init are not closed
+	fs, err := log.OpenFile(fname, log.FileOptions{})
+	assert.FailOnErr(t, err)
+	defer fs.Close()
+	log.Init(fs, log.LevelError, false)
+	log.Shutdown()
+	_, err = fs.Write([]byte("still open\n"))
+	assert.FailOnErr(t, err)
+	log.Init(os.Stdout, log.LevelError, false)
+}
+
+func TestPanic(t *testing.T) {
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	log.Init(out, log.LevelError, false)
+
+	called := false
+	log.OnShutdown(func() { called = true })
+	defer log.Shutdown()
+
+	var value interface{}
+	func() {
+		defer func() { value = recover() }()
+		log.Panicf("invalid state %d", 12, log.F("key", "value"))
+	}()
+	assert.Equal(t, value, "invalid state 12")
+	assert.Equal(t, called, false)
+	assert.FailIfNot(t, strings.HasSuffix(buf.String(), "CRITICAL  invalid state 12 key=value\n"),
+		"unexpected output: %s", buf.String())
+
+	buf.Reset()
+	func() {
+		defer func() { value = recover() }()
+		log.Panic("panic", 1)
+	}()
+	assert.Equal(t, value, "panic1")
+	assert.FailIfNot(t, strings.HasSuffix(buf.String(), "CRITICAL  [[panic 1]]\n"),
+		"unexpected output: %s", buf.String())
+
+	buf.Reset()
+	assert.Panics(t, func() { log.Panic("panic") })
+	assert.FailIfNot(t, strings.HasSuffix(buf.String(), "CRITICAL  [[panic]]\n"),
+		"unexpected output: %s", buf.String())
+}
@@ -0,0 +1,137 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileOptions configures a FileSink.
+type FileOptions struct {
+	// FileMode is the permission used for creating the log file. If it is 0,
+	// 0600 will be used.
+	FileMode os.FileMode
+	// DirMode is the permission used for creating missing directories of the
+	// log file. If it is 0, 0700 will be used.
+	DirMode os.FileMode
+	// Sync enables syncing the log file to the disk after writing a record
+	// with SyncLevel or a more severe level.
+	Sync bool
+	// SyncLevel is the least severe level, which causes a sync, if Sync is
+	// enabled.
+	SyncLevel Level
+	// OnError is invoked, if writing to or syncing the log file fails. If it
+	// is nil, the error will be written to the standard error. For records
+	// written by the logging functions, OnError is invoked after the records
+	// were written, so that it may use the logging functions itself.
+	OnError func(err error)
+}
+
+// FileSink is a log file, which is safe for concurrent use. Records are
+// appended to the file with a single write each, so that multiple processes
+// can write to the same file.
+type FileSink struct {
+	fp   *os.File
+	opts FileOptions
+	mux  sync.Mutex
+	// reporting is true, while OnError is invoked.
+	reporting bool
+}
+
+// OpenFile opens the passed log file for appending records. Missing
+// directories and the file itself are created with the permissions
+// configured in opts.
+func OpenFile(logfile string, opts FileOptions) (*FileSink, error) {
+	if opts.FileMode == 0 {
+		opts.FileMode = 0600
+	}
+	if opts.DirMode == 0 {
+		opts.DirMode = 0700
+	}
+	if err := os.MkdirAll(filepath.Dir(logfile), opts.DirMode); err != nil {
+		return nil, err
+	}
+	fp, err := os.OpenFile(logfile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, opts.FileMode)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{fp: fp, opts: opts}, nil
+}
+
+// Name returns the name of the log file.
+func (fs *FileSink) Name() string {
+	return fs.fp.Name()
+}
+
+// Write appends p to the log file. Errors are reported via the OnError
+// callback of the FileOptions.
+func (fs *FileSink) Write(p []byte) (int, error) {
+	n, err := fs.write(p)
+	if err != nil {
+		fs.report(err)
+	}
+	return n, err
+}
+
+// write appends p to the log file without reporting errors.
+func (fs *FileSink) write(p []byte) (int, error) {
+	fs.mux.Lock()
+	defer fs.mux.Unlock()
+	return fs.fp.Write(p)
+}
+
+// Sync commits the contents of the log file to the disk. Errors are reported
+// via the OnError callback of the FileOptions.
+func (fs *FileSink) Sync() error {
+	err := fs.sync()
+	if err != nil {
+		fs.report(err)
+	}
+	return err
+}
+
+// sync commits the contents of the log file to the disk without reporting
+// errors.
+func (fs *FileSink) sync() error {
+	fs.mux.Lock()
+	defer fs.mux.Unlock()
+	return fs.fp.Sync()
+}
+
+// Close closes the log file.
+func (fs *FileSink) Close() error {
+	fs.mux.Lock()
+	defer fs.mux.Unlock()
+	return fs.fp.Close()
+}
+
+// written syncs the log file, if a record with the passed level has been
+// written and syncing is enabled for it. Errors are returned without
+// reporting them.
+func (fs *FileSink) written(level Level) error {
+	if fs.opts.Sync && level <= fs.opts.SyncLevel {
+		return fs.sync()
+	}
+	return nil
+}
+
+// report reports the passed error via OnError. Errors occurring while
+// OnError is invoked, e.g. because it logs to the failing FileSink, are
+// written to the standard error to avoid an endless recursion.
+func (fs *FileSink) report(err error) {
+	fs.mux.Lock()
+	reporting := fs.reporting
+	fs.reporting = true
+	fs.mux.Unlock()
+	if fs.opts.OnError == nil || reporting {
+		fmt.Fprintf(os.Stderr, "log: writing to '%s' failed: %v\n", fs.fp.Name(), err)
+	} else {
+		fs.opts.OnError(err)
+	}
+	if !reporting {
+		fs.mux.Lock()
+		fs.reporting = false
+		fs.mux.Unlock()
+	}
+}
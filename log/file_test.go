@@ -0,0 +1,108 @@
+package log_test
+
+import (
+	"errors"
+	"github.com/marcusva/gadget/log"
+	"github.com/marcusva/gadget/testing/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenFile(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "gadget-logtest")
+	assert.FailOnErr(t, err)
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "sub", "dir", "app.log")
+	fs, err := log.OpenFile(fname, log.FileOptions{FileMode: 0640, DirMode: 0750})
+	assert.FailOnErr(t, err)
+	assert.Equal(t, fs.Name(), fname)
+	_, err = fs.Write([]byte("first\n"))
+	assert.NoErr(t, err)
+	assert.NoErr(t, fs.Close())
+
+	info, err := os.Stat(fname)
+	assert.FailOnErr(t, err)
+	// The umask may remove permissions, but none are added
+	assert.Equal(t, info.Mode().Perm()&^0640, os.FileMode(0))
+	info, err = os.Stat(filepath.Dir(fname))
+	assert.FailOnErr(t, err)
+	assert.Equal(t, info.Mode().Perm()&^0750, os.FileMode(0))
+
+	// Existing content is kept
+	assert.FailOnErr(t, log.InitFileOptions(fname, log.LevelDebug, false,
+		log.FileOptions{Sync: true, SyncLevel: log.LevelError}))
+	log.Info("second")
+	log.Error("third")
+	log.Init(ioutil.Discard, log.LevelError, false)
+
+	data, err := ioutil.ReadFile(fname)
+	assert.FailOnErr(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.FailIfNot(t, len(lines) == 3, "unexpected content: %s", data)
+	assert.Equal(t, lines[0], "first")
+	assert.FailIfNot(t, strings.HasSuffix(lines[1], "[[second]]"), "unexpected content: %s", data)
+	assert.FailIfNot(t, strings.HasSuffix(lines[2], "[[third]]"), "unexpected content: %s", data)
+
+	_, err = log.OpenFile(filepath.Join(fname, "invalid.log"), log.FileOptions{})
+	assert.Err(t, err)
+}
+
+func TestFileSinkOnError(t *testing.T) {
+	fp, err := ioutil.TempFile(os.TempDir(), "gadget-logtest")
+	assert.FailOnErr(t, err)
+	fname := fp.Name()
+	fp.Close()
+	defer os.Remove(fname)
+
+	var errs []error
+	fs, err := log.OpenFile(fname, log.FileOptions{
+		OnError: func(err error) { errs = append(errs, err) },
+	})
+	assert.FailOnErr(t, err)
+	assert.NoErr(t, fs.Close())
+
+	_, err = fs.Write([]byte("closed\n"))
+	assert.Err(t, err)
+	assert.Err(t, fs.Sync())
+	assert.Equal(t, len(errs), 2)
+}
+
+func TestFileSinkOnErrorLogging(t *testing.T) {
+	fp, err := ioutil.TempFile(os.TempDir(), "gadget-logtest")
+	assert.FailOnErr(t, err)
+	fname := fp.Name()
+	fp.Close()
+	defer os.Remove(fname)
+
+	var errs []error
+	fs, err := log.OpenFile(fname, log.FileOptions{
+		Sync: true,
+		OnError: func(err error) {
+			errs = append(errs, err)
+			// Logging to the failing file must neither deadlock nor recurse.
+			log.Warning("write failed", err)
+		},
+	})
+	assert.FailOnErr(t, err)
+	log.Init(fs, log.LevelDebug, false)
+	defer log.Init(ioutil.Discard, log.LevelError, false)
+	assert.NoErr(t, fs.Close())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		log.Error("closed")
+		log.Flush()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		assert.FailOnErr(t, errors.New("logging to a failing file blocks"))
+	}
+	assert.Equal(t, len(errs), 2)
+}
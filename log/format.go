@@ -0,0 +1,201 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formatter turns a Record into the bytes written to the log output.
+type Formatter interface {
+	// Format formats the passed Record, including the trailing newline.
+	Format(rec *Record) []byte
+}
+
+const (
+	// DefaultLayout is the layout used by the TextFormatter, if no layout
+	// is set.
+	DefaultLayout = "{prefix}{time} {level} {caller} {message} {fields}"
+
+	// TimeNone disables the timestamp, e.g. if the output is passed to the
+	// systemd journal, which adds its own timestamps.
+	TimeNone = "-"
+	// TimeRFC3339Milli is a RFC 3339 timestamp with milliseconds.
+	TimeRFC3339Milli = "2006-01-02T15:04:05.000Z07:00"
+	// TimeRFC3339Micro is a RFC 3339 timestamp with microseconds.
+	TimeRFC3339Micro = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// TextFormatter writes records in a plain text format. By default, this is
+//
+//	2006/01/02 15:04:05 LEVEL     [file.go:12] message key=value
+//
+// The caller information is only written, if it is set in the Record.
+//
+// The Layout defines the order of the parts of a line. It may contain the
+// following placeholders, all other text is written as is:
+//
+//	{prefix}   the Prefix of the TextFormatter
+//	{time}     the timestamp formatted with TimeFormat
+//	{level}    the level name, padded to the longest level name
+//	{caller}   the caller information as [file.go:12]
+//	{message}  the message
+//	{fields}   the fields as key=value pairs
+//
+// If a placeholder results in an empty string, the following space is
+// omitted.
+type TextFormatter struct {
+	// Layout is the layout of a line. If it is empty, DefaultLayout is used.
+	Layout string
+	// TimeFormat is the layout to use for time.Format(). If it is empty,
+	// TimeLayout is used. If it is TimeNone, no timestamp will be written.
+	TimeFormat string
+	// UTC writes the timestamp in UTC instead of the local time.
+	UTC bool
+	// Prefix is written in place of the {prefix} placeholder.
+	Prefix string
+}
+
+// Format formats the passed Record, including the trailing newline.
+func (f TextFormatter) Format(rec *Record) []byte {
+	return append(f.format(rec, nil), '\n')
+}
+
+// format formats the passed Record. If colors is not nil, the individual
+// parts will be wrapped in the escape sequences provided by it.
+func (f TextFormatter) format(rec *Record, colors *palette) []byte {
+	layout := f.Layout
+	if layout == "" {
+		layout = DefaultLayout
+	}
+	var buf bytes.Buffer
+	skipSpace := false
+	for len(layout) > 0 {
+		// Braces, which do not enclose a placeholder, are written as is.
+		start := strings.IndexByte(layout, '{')
+		end := -1
+		if start != -1 {
+			end = strings.IndexByte(layout[start:], '}')
+		}
+		if end == -1 {
+			start, end = len(layout), len(layout)-1
+		} else {
+			end += start
+			start = strings.LastIndexByte(layout[:end], '{')
+		}
+		literal := layout[:start]
+		if skipSpace {
+			literal = strings.TrimPrefix(literal, " ")
+		}
+		buf.WriteString(literal)
+		if start == len(layout) {
+			break
+		}
+		before := buf.Len()
+		if !f.placeholder(&buf, layout[start+1:end], rec, colors) {
+			buf.WriteString(layout[start : end+1])
+		}
+		skipSpace = buf.Len() == before
+		layout = layout[end+1:]
+	}
+	if skipSpace && buf.Len() > 0 && buf.Bytes()[buf.Len()-1] == ' ' {
+		buf.Truncate(buf.Len() - 1)
+	}
+	return buf.Bytes()
+}
+
+// placeholder writes the value for the named placeholder. It returns false,
+// if the placeholder is unknown.
+func (f TextFormatter) placeholder(buf *bytes.Buffer, name string, rec *Record, colors *palette) bool {
+	switch name {
+	case "prefix":
+		buf.WriteString(f.Prefix)
+	case "time":
+		switch f.TimeFormat {
+		case TimeNone:
+		case "":
+			buf.WriteString(f.time(rec).Format(TimeLayout))
+		default:
+			buf.WriteString(f.time(rec).Format(f.TimeFormat))
+		}
+	case "level":
+		if colors != nil {
+			buf.WriteString(colors.level(rec.Level))
+			fmt.Fprintf(buf, "%-9s", rec.Level)
+			buf.WriteString(colorReset)
+		} else {
+			fmt.Fprintf(buf, "%-9s", rec.Level)
+		}
+	case "caller":
+		if rec.File != "" {
+			buf.WriteByte('[')
+			buf.WriteString(rec.File)
+			buf.WriteByte(':')
+			buf.WriteString(strconv.Itoa(rec.Line))
+			buf.WriteByte(']')
+		}
+	case "message":
+		buf.WriteString(rec.Message)
+	case "fields":
+		for idx, field := range rec.Fields {
+			if idx > 0 {
+				buf.WriteByte(' ')
+			}
+			if colors != nil && colors.fields {
+				buf.WriteString(colorField)
+				buf.WriteString(field.Key)
+				buf.WriteString(colorReset)
+				buf.WriteString(field.String()[len(field.Key):])
+			} else {
+				buf.WriteString(field.String())
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func (f TextFormatter) time(rec *Record) time.Time {
+	if f.UTC {
+		return rec.Time.UTC()
+	}
+	return rec.Time
+}
+
+// InJournal checks, if the process runs as systemd service with its output
+// connected to the journal. In this case, the TimeFormat can be set to
+// TimeNone, since the journal records the time on its own.
+func InJournal() bool {
+	return os.Getenv("JOURNAL_STREAM") != ""
+}
+
+// splitFields separates the Field values from the other arguments.
+func splitFields(args []interface{}) ([]interface{}, []Field) {
+	var fields []Field
+	for _, arg := range args {
+		if _, ok := arg.(Field); ok {
+			fields = make([]Field, 0, len(args))
+			break
+		}
+	}
+	if fields == nil {
+		return args, nil
+	}
+	values := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		if field, ok := arg.(Field); ok {
+			if ev, ok := field.Value.(errorValue); ok {
+				fields = append(fields, errorFields(field.Key, ev.err)...)
+			} else {
+				fields = append(fields, field)
+			}
+		} else {
+			values = append(values, arg)
+		}
+	}
+	return values, fields
+}
@@ -0,0 +1,93 @@
+package log_test
+
+import (
+	"bytes"
+	"github.com/marcusva/gadget/log"
+	"github.com/marcusva/gadget/testing/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFields(t *testing.T) {
+	var buf bytes.Buffer
+	log.Init(&buf, log.LevelDebug, false)
+
+	log.Info("login", log.F("user", "jdoe"), log.F("remote", "10.0.0.1 (vpn)"))
+	result := strings.TrimSpace(buf.String())
+	assert.FailIfNot(t, strings.HasSuffix(result, `[[login]] user=jdoe remote="10.0.0.1 (vpn)"`),
+		"unexpected output: %s", result)
+	buf.Reset()
+
+	log.Errorf("request %s failed", "/index", log.F("status", 500))
+	result = strings.TrimSpace(buf.String())
+	assert.FailIfNot(t, strings.HasSuffix(result, "request /index failed status=500"),
+		"unexpected output: %s", result)
+}
+
+func TestFieldString(t *testing.T) {
+	assert.Equal(t, log.F("k", 12).String(), "k=12")
+	assert.Equal(t, log.F("k", "").String(), `k=""`)
+	assert.Equal(t, log.F("k", `a "b"`).String(), `k="a \"b\""`)
+}
+
+func TestTextFormatter(t *testing.T) {
+	rec := &log.Record{
+		Time:    time.Date(2018, 1, 2, 15, 4, 5, 0, time.Local),
+		Level:   log.LevelNotice,
+		File:    "main.go",
+		Line:    12,
+		Message: "started",
+		Fields:  []log.Field{log.F("pid", 42)},
+	}
+	out := log.TextFormatter{}.Format(rec)
+	assert.Equal(t, string(out), "2018/01/02 15:04:05 NOTICE    [main.go:12] started pid=42\n")
+}
+
+func TestTextFormatterLayout(t *testing.T) {
+	rec := &log.Record{
+		Time:    time.Date(2018, 1, 2, 15, 4, 5, 123456789, time.FixedZone("CET", 3600)),
+		Level:   log.LevelInfo,
+		Message: "started",
+	}
+
+	f := log.TextFormatter{
+		Layout:     "{level}|{time}|{message}",
+		TimeFormat: log.TimeRFC3339Micro,
+	}
+	assert.Equal(t, string(f.Format(rec)), "INFO     |2018-01-02T15:04:05.123456+01:00|started\n")
+
+	f.UTC = true
+	assert.Equal(t, string(f.Format(rec)), "INFO     |2018-01-02T14:04:05.123456Z|started\n")
+
+	// Empty placeholders do not leave duplicate spaces
+	f = log.TextFormatter{TimeFormat: log.TimeNone, Prefix: "app: "}
+	assert.Equal(t, string(f.Format(rec)), "app: INFO      started\n")
+
+	f = log.TextFormatter{Layout: "{message} {fields} {caller}", TimeFormat: log.TimeNone}
+	assert.Equal(t, string(f.Format(rec)), "started\n")
+
+	// Unknown placeholders are written as is
+	f = log.TextFormatter{Layout: "{level} {unknown} {message", TimeFormat: log.TimeNone}
+	assert.Equal(t, string(f.Format(rec)), "INFO      {unknown} {message\n")
+
+	// Stray braces are written as is
+	f = log.TextFormatter{Layout: "} {level}|{{message}}|{", TimeFormat: log.TimeNone}
+	assert.Equal(t, string(f.Format(rec)), "} INFO     |{started}|{\n")
+}
+
+func TestSetFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	log.Init(&buf, log.LevelDebug, false)
+	log.SetFormatter(log.NewConsoleFormatter(&buf, log.ColorAlways))
+	log.Info("test")
+	assert.FailIfNot(t, strings.Contains(buf.String(), "\x1b["),
+		"no colors in %q", buf.String())
+	buf.Reset()
+
+	// Init resets the formatter
+	log.Init(&buf, log.LevelDebug, false)
+	log.Info("test")
+	assert.FailIf(t, strings.Contains(buf.String(), "\x1b["),
+		"colors in %q", buf.String())
+}
@@ -0,0 +1,64 @@
+package log
+
+import (
+	"sync"
+)
+
+// Hook is a function, which is invoked for records written to the log.
+// The passed Record must not be modified.
+type Hook func(rec *Record)
+
+type hookEntry struct {
+	level Level
+	async bool
+	hook  Hook
+}
+
+var (
+	hooks   []hookEntry
+	hookMux = sync.RWMutex{}
+)
+
+// AddHook registers a Hook, which is invoked for each record written to the
+// log with the passed level or a more severe one, e.g. to send a page on
+// LevelCritical and above. Records discarded due to the current threshold do
+// not invoke any hook.
+// If async is true, the hook runs in its own goroutine, otherwise the logging
+// function returns after the hook has finished. Hooks are invoked after the
+// record has been written, so that they can use the logging functions.
+func AddHook(level Level, async bool, hook Hook) {
+	hookMux.Lock()
+	defer hookMux.Unlock()
+	hooks = append(hooks, hookEntry{level: level, async: async, hook: hook})
+}
+
+// ClearHooks removes all registered hooks.
+func ClearHooks() {
+	hookMux.Lock()
+	defer hookMux.Unlock()
+	hooks = nil
+}
+
+// runHooks invokes all hooks for the passed record. rec may be nil, if no
+// record was written.
+func runHooks(rec *Record) {
+	if rec == nil {
+		return
+	}
+	// Hooks run without holding the lock, so that they can register or
+	// remove hooks.
+	hookMux.RLock()
+	entries := make([]hookEntry, len(hooks))
+	copy(entries, hooks)
+	hookMux.RUnlock()
+	for _, entry := range entries {
+		if rec.Level > entry.level {
+			continue
+		}
+		if entry.async {
+			go entry.hook(rec)
+		} else {
+			entry.hook(rec)
+		}
+	}
+}
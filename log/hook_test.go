@@ -0,0 +1,67 @@
+package log_test
+
+import (
+	"bytes"
+	"github.com/marcusva/gadget/log"
+	"github.com/marcusva/gadget/testing/assert"
+	"testing"
+	"time"
+)
+
+func TestAddHook(t *testing.T) {
+	defer log.ClearHooks()
+	var buf bytes.Buffer
+	log.Init(&buf, log.LevelInfo, false)
+
+	var records []*log.Record
+	log.AddHook(log.LevelCritical, false, func(rec *log.Record) {
+		records = append(records, rec)
+		// Hooks may use the logging functions
+		log.Info("hook invoked")
+	})
+	done := make(chan *log.Record, 4)
+	log.AddHook(log.LevelError, true, func(rec *log.Record) {
+		done <- rec
+	})
+
+	log.Warning("warning")
+	log.Criticalf("critical %d", 1)
+	log.Emergency("emergency")
+	log.Debug("debug")
+	assert.FailIfNot(t, len(records) == 2, "unexpected records: %v", records)
+	assert.Equal(t, records[0].Message, "critical 1")
+	assert.Equal(t, records[1].Level, log.LevelEmergency)
+
+	select {
+	case rec := <-done:
+		assert.FailIf(t, rec.Level > log.LevelError, "invalid level %v", rec.Level)
+	case <-time.After(time.Second):
+		t.Error("async hook was not invoked")
+	}
+
+	// Records below the threshold do not invoke hooks
+	records = nil
+	log.Init(&buf, log.LevelEmergency, false)
+	log.Critical("critical")
+	assert.Equal(t, len(records), 0)
+
+	log.ClearHooks()
+	log.Emergency("emergency")
+	assert.Equal(t, len(records), 0)
+}
+
+func TestAddHookWithinHook(t *testing.T) {
+	defer log.ClearHooks()
+	var buf bytes.Buffer
+	log.Init(&buf, log.LevelInfo, false)
+
+	added := 0
+	log.AddHook(log.LevelError, false, func(rec *log.Record) {
+		// Must not deadlock
+		log.AddHook(log.LevelError, false, func(rec *log.Record) { added++ })
+	})
+	log.Error("first")
+	assert.Equal(t, added, 0)
+	log.Error("second")
+	assert.Equal(t, added, 1)
+}
@@ -0,0 +1,52 @@
+package log
+
+// Lazy is a lazily evaluated argument for the logging functions. The function
+// is only invoked, if the record is actually written, e.g. if the threshold
+// allows it:
+//
+//	log.Debug("state", log.Lazy(func() interface{} { return dumpState() }))
+//
+// Besides Lazy, arguments and Field values of the types func() interface{}
+// and func() string are evaluated lazily as well. Values implementing
+// fmt.Stringer or error are only converted to a string, if the record is
+// written.
+type Lazy func() interface{}
+
+// evaluate returns the result of a lazily evaluated value and true, or the
+// unmodified value and false, if value is not lazy.
+func evaluate(value interface{}) (interface{}, bool) {
+	switch fn := value.(type) {
+	case Lazy:
+		return fn(), true
+	case func() interface{}:
+		return fn(), true
+	case func() string:
+		return fn(), true
+	case Field:
+		if result, ok := evaluate(fn.Value); ok {
+			return Field{Key: fn.Key, Value: result}, true
+		}
+	}
+	return value, false
+}
+
+// resolve evaluates all lazy arguments and fields. The passed slice is not
+// modified.
+func resolve(args []interface{}) []interface{} {
+	var result []interface{}
+	for idx, arg := range args {
+		value, ok := evaluate(arg)
+		if !ok {
+			continue
+		}
+		if result == nil {
+			result = make([]interface{}, len(args))
+			copy(result, args)
+		}
+		result[idx] = value
+	}
+	if result == nil {
+		return args
+	}
+	return result
+}
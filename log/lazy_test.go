@@ -0,0 +1,85 @@
+package log_test
+
+import (
+	"bytes"
+	"github.com/marcusva/gadget/log"
+	"github.com/marcusva/gadget/testing/assert"
+	"strings"
+	"testing"
+)
+
+type countingStringer struct {
+	calls int
+}
+
+func (s *countingStringer) String() string {
+	s.calls++
+	return "stringer"
+}
+
+func TestLazy(t *testing.T) {
+	var buf bytes.Buffer
+	log.Init(&buf, log.LevelInfo, false)
+
+	calls := 0
+	lazy := log.Lazy(func() interface{} {
+		calls++
+		return "expensive"
+	})
+	fn := func() string {
+		calls++
+		return "function"
+	}
+	stringer := &countingStringer{}
+
+	log.Debug("skipped", lazy, fn, log.F("state", lazy))
+	log.Debugf("skipped %v %v %v", lazy, fn, stringer)
+	assert.Equal(t, calls, 0)
+	assert.Equal(t, stringer.calls, 0)
+	assert.Equal(t, buf.Len(), 0)
+
+	args := []interface{}{lazy, log.F("state", lazy)}
+	log.Infof("value: %v", args...)
+	assert.Equal(t, calls, 2)
+	result := strings.TrimSpace(buf.String())
+	assert.FailIfNot(t, strings.HasSuffix(result, "value: expensive state=expensive"),
+		"unexpected output: %s", result)
+	// The passed arguments are not modified
+	_, ok := args[0].(log.Lazy)
+	assert.Equal(t, ok, true)
+	buf.Reset()
+
+	log.Info(fn, stringer)
+	assert.Equal(t, calls, 3)
+	assert.Equal(t, stringer.calls, 1)
+	result = strings.TrimSpace(buf.String())
+	assert.FailIfNot(t, strings.HasSuffix(result, "[[function stringer]]"),
+		"unexpected output: %s", result)
+}
+
+type loggingStringer struct{}
+
+func (s loggingStringer) String() string {
+	log.Debug("inner")
+	return "outer"
+}
+
+func TestLazyLogging(t *testing.T) {
+	var buf bytes.Buffer
+	log.Init(&buf, log.LevelDebug, false)
+	log.SetFormatter(log.TextFormatter{TimeFormat: log.TimeNone})
+
+	// Lazy values and Stringers may use the logging functions
+	log.Info(log.Lazy(func() interface{} {
+		log.Debug("lazy")
+		return "value"
+	}))
+	log.Infof("%v", loggingStringer{})
+	log.Info("field", log.F("key", func() string {
+		log.Debug("field")
+		return "value"
+	}))
+	assert.Equal(t, buf.String(), "DEBUG     [[lazy]]\nINFO      [[value]]\n"+
+		"DEBUG     [[inner]]\nINFO      outer\n"+
+		"DEBUG     [[field]]\nINFO      [[field]] key=value\n")
+}
@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"sync"
+	"time"
 )
 
 const (
@@ -37,7 +38,9 @@ type Level int8
 
 var (
 	logger     *log.Logger
-	fpLogfile  *os.File
+	sink       io.Writer
+	formatter  Formatter
+	logfile    *FileSink
 	showCaller bool
 	threshold  Level
 	mux        = sync.Mutex{}
@@ -50,7 +53,9 @@ func init() {
 	}
 }
 
-// Logger gets the logger being used.
+// Logger gets the logger being used. The logger writes to the current log
+// output with the standard flags of the log package and bypasses the
+// threshold and Formatter.
 func Logger() *log.Logger {
 	mux.Lock()
 	defer mux.Unlock()
@@ -90,17 +95,27 @@ func GetLogLevel(level string) (Level, error) {
 }
 
 // InitFile initializes the logging functionality using the passed file.
+// Records are appended to the file, which will be created, if it does not
+// exist, using the default FileOptions.
 // This will close the currently open logfile, if the logger has been
 // initialized with InitFile before.
-func InitFile(logfile string, level Level, caller bool) error {
-	logfp, err := os.OpenFile(logfile, os.O_RDWR, os.FileMode(0600))
+func InitFile(filename string, level Level, caller bool) error {
+	return InitFileOptions(filename, level, caller, FileOptions{})
+}
+
+// InitFileOptions initializes the logging functionality using the passed
+// file, which is opened via OpenFile with the passed FileOptions.
+// This will close the currently open logfile, if the logger has been
+// initialized with InitFile before.
+func InitFileOptions(filename string, level Level, caller bool, opts FileOptions) error {
+	fs, err := OpenFile(filename, opts)
 	if err != nil {
 		return err
 	}
-	Init(logfp, level, caller)
+	Init(fs, level, caller)
 	mux.Lock()
 	defer mux.Unlock()
-	fpLogfile = logfp
+	logfile = fs
 	return nil
 }
 
@@ -111,187 +126,242 @@ func InitFile(logfile string, level Level, caller bool) error {
 func Init(out io.Writer, level Level, caller bool) {
 	mux.Lock()
 	defer mux.Unlock()
-	if fpLogfile != nil {
-		fpLogfile.Close()
-		fpLogfile = nil
+	if logfile != nil {
+		logfile.Close()
+		logfile = nil
 	}
 	threshold = level
 	showCaller = caller
+	formatter = TextFormatter{}
+	recorder = nil
+	sink = out
 	logger = log.New(out, "", log.LstdFlags)
 }
 
 // NoisyInit initializes the logging functionality with a debug level on the
-// standard output.
+// standard output. The levels will be colored, if the standard output is a
+// terminal and the NO_COLOR environment variable is not set.
 // This will close the currently open logfile, if the logger has been
 // initialized with InitFile before.
 func NoisyInit() {
 	Init(os.Stdout, LevelDebug, true)
+	SetFormatter(NewConsoleFormatter(os.Stdout, ColorAuto))
 }
 
-func _printval(prefix string, v ...interface{}) {
-	if showCaller {
-		// Arg to Caller(): 0 = this func, 1 = previous (_log.XXX), 2: caller
-		if _, file, line, ok := runtime.Caller(2); ok {
-			fdata := fmt.Sprintf("[%s:%d]", filepath.Base(file), line)
-			logger.Printf("%-9s %s %v\n", prefix, fdata, v)
-			return
+// SetFormatter sets the Formatter to use for writing records. Init resets the
+// Formatter to a TextFormatter.
+func SetFormatter(f Formatter) {
+	mux.Lock()
+	defer mux.Unlock()
+	formatter = f
+}
+
+// enabled checks, if a record with the passed level needs to be created.
+func enabled(level Level) bool {
+	return threshold >= level || recorder != nil
+}
+
+// output writes a record with the passed message and fields to the log.
+// calldepth is the amount of stack frames to skip to get to the caller of the
+// logging function. If calldepth is negative, no caller information will be
+// added.
+// output returns the written record or nil, if the record was only kept by the
+// flight recorder.
+func output(level Level, calldepth int, message string, fields []Field) *Record {
+	rec := &Record{
+		Time:    time.Now(),
+		Level:   level,
+		Message: message,
+		Fields:  fields,
+	}
+	if showCaller && calldepth >= 0 {
+		if _, file, line, ok := runtime.Caller(calldepth + 1); ok {
+			rec.File = filepath.Base(file)
+			rec.Line = line
 		}
 	}
-	logger.Printf("%-9s %v\n", prefix, v)
+	written := threshold >= level
+	if recorder != nil {
+		if written && level <= recorder.trigger {
+			recorder.dump(write)
+		}
+		recorder.add(rec, written)
+	}
+	if !written {
+		return nil
+	}
+	write(rec)
+	count(level)
+	return rec
 }
 
-func _printstr(prefix string, output string) {
-	if showCaller {
-		// Arg to Caller(): 0 = this func, 1 = previous (_log.XXX), 2: caller
-		if _, file, line, ok := runtime.Caller(2); ok {
-			fdata := fmt.Sprintf("[%s:%d]", filepath.Base(file), line)
-			logger.Printf("%-9s %s %s\n", prefix, fdata, output)
-			return
-		}
+// write writes the passed record to the log.
+func write(rec *Record) {
+	data := formatter.Format(rec)
+	fs, ok := sink.(*FileSink)
+	if !ok {
+		sink.Write(data)
+		return
+	}
+	_, err := fs.write(data)
+	if err == nil {
+		err = fs.written(rec.Level)
 	}
-	logger.Printf("%-9s %s\n", prefix, output)
+	failed(fs, err)
 }
 
-// Debug writes a debug message to the log.
-func Debug(args ...interface{}) {
+// sinkError is an error of a FileSink, which occurred while holding the lock.
+type sinkError struct {
+	fs  *FileSink
+	err error
+}
+
+// sinkErrors are reported via reportErrors after releasing the lock, so that
+// the OnError callbacks may use the logging functions.
+var sinkErrors []sinkError
+
+// failed queues the error of a FileSink, if it is not nil. The lock has to be
+// held.
+func failed(fs *FileSink, err error) {
+	if err != nil {
+		sinkErrors = append(sinkErrors, sinkError{fs, err})
+	}
+}
+
+// reportErrors reports the queued errors of the FileSinks. The lock must not
+// be held.
+func reportErrors() {
 	mux.Lock()
-	defer mux.Unlock()
-	if threshold >= LevelDebug {
-		_printval("DEBUG", args)
+	errs := sinkErrors
+	sinkErrors = nil
+	mux.Unlock()
+	for _, e := range errs {
+		e.fs.report(e.err)
 	}
 }
 
-// Debugf writes a debug message to the log.
-func Debugf(format string, args ...interface{}) {
+// valueMessage creates the message and fields for the passed arguments of
+// the non-formatting logging functions.
+func valueMessage(args []interface{}) (string, []Field) {
+	values, fields := splitFields(resolve(args))
+	return fmt.Sprintf("%v", []interface{}{values}), fields
+}
+
+// isEnabled checks, if a record with the passed level needs to be created.
+func isEnabled(level Level) bool {
 	mux.Lock()
 	defer mux.Unlock()
-	if threshold >= LevelDebug {
-		_printstr("DEBUG", fmt.Sprintf(format, args...))
+	return enabled(level)
+}
+
+func _printval(level Level, args []interface{}) {
+	if isEnabled(level) {
+		// The arguments are evaluated without holding the lock, so that lazy
+		// values and fmt.Stringer implementations can use the logging
+		// functions.
+		msg, fields := valueMessage(args)
+		// 0 = output, 1 = _printmsg, 2 = this func, 3 = log.XXX, 4: caller
+		_printmsg(level, 3, msg, fields)
 	}
 }
 
-// Info writes an informational message to the log.
-func Info(args ...interface{}) {
+func _printstr(level Level, format string, args []interface{}) {
+	if isEnabled(level) {
+		values, fields := splitFields(resolve(args))
+		_printmsg(level, 3, fmt.Sprintf(format, values...), fields)
+	}
+}
+
+func _printmsg(level Level, calldepth int, msg string, fields []Field) {
+	var rec *Record
+	// Errors are reported and hooks run after the lock has been released.
+	defer func() {
+		reportErrors()
+		runHooks(rec)
+	}()
 	mux.Lock()
 	defer mux.Unlock()
-	if threshold >= LevelInfo {
-		_printval("INFO", args)
+	if enabled(level) {
+		rec = output(level, calldepth, msg, fields)
 	}
 }
 
+// Debug writes a debug message to the log.
+func Debug(args ...interface{}) {
+	_printval(LevelDebug, args)
+}
+
+// Debugf writes a debug message to the log.
+func Debugf(format string, args ...interface{}) {
+	_printstr(LevelDebug, format, args)
+}
+
+// Info writes an informational message to the log.
+func Info(args ...interface{}) {
+	_printval(LevelInfo, args)
+}
+
 // Infof writes an informational message to the log.
 func Infof(format string, args ...interface{}) {
-	mux.Lock()
-	defer mux.Unlock()
-	if threshold >= LevelInfo {
-		_printstr("INFO", fmt.Sprintf(format, args...))
-	}
+	_printstr(LevelInfo, format, args)
 }
 
 // Notice writes a notice message to the log.
 func Notice(args ...interface{}) {
-	mux.Lock()
-	defer mux.Unlock()
-	if threshold >= LevelNotice {
-		_printval("NOTICE", args)
-	}
+	_printval(LevelNotice, args)
 }
 
 // Noticef writes a notice message to the log.
 func Noticef(format string, args ...interface{}) {
-	mux.Lock()
-	defer mux.Unlock()
-	if threshold >= LevelNotice {
-		_printstr("NOTICE", fmt.Sprintf(format, args...))
-	}
+	_printstr(LevelNotice, format, args)
 }
 
 // Warning writes a warning message to the log.
 func Warning(args ...interface{}) {
-	mux.Lock()
-	defer mux.Unlock()
-	if threshold >= LevelWarning {
-		_printval("WARNING", args)
-	}
+	_printval(LevelWarning, args)
 }
 
 // Warningf writes a warning message to the log.
 func Warningf(format string, args ...interface{}) {
-	mux.Lock()
-	defer mux.Unlock()
-	if threshold >= LevelWarning {
-		_printstr("WARNING", fmt.Sprintf(format, args...))
-	}
+	_printstr(LevelWarning, format, args)
 }
 
 // Error writes an error message to the log.
 func Error(args ...interface{}) {
-	mux.Lock()
-	defer mux.Unlock()
-	if threshold >= LevelError {
-		_printval("ERROR", args)
-	}
+	_printval(LevelError, args)
 }
 
 // Errorf writes an error message to the log.
 func Errorf(format string, args ...interface{}) {
-	mux.Lock()
-	defer mux.Unlock()
-	if threshold >= LevelError {
-		_printstr("ERROR", fmt.Sprintf(format, args...))
-	}
+	_printstr(LevelError, format, args)
 }
 
 // Critical writes a critical message to the log.
 func Critical(args ...interface{}) {
-	mux.Lock()
-	defer mux.Unlock()
-	if threshold >= LevelCritical {
-		_printval("CRITICAL", args)
-	}
+	_printval(LevelCritical, args)
 }
 
 // Criticalf writes a critical message to the log.
 func Criticalf(format string, args ...interface{}) {
-	mux.Lock()
-	defer mux.Unlock()
-	if threshold >= LevelCritical {
-		_printstr("CRITICAL", fmt.Sprintf(format, args...))
-	}
+	_printstr(LevelCritical, format, args)
 }
 
 // Alert writes an alert message to the log.
 func Alert(args ...interface{}) {
-	mux.Lock()
-	defer mux.Unlock()
-	if threshold >= LevelAlert {
-		_printval("ALERT", args)
-	}
+	_printval(LevelAlert, args)
 }
 
 // Alertf rites an alert message to the log.
 func Alertf(format string, args ...interface{}) {
-	mux.Lock()
-	defer mux.Unlock()
-	if threshold >= LevelAlert {
-		_printstr("ALERT", fmt.Sprintf(format, args...))
-	}
+	_printstr(LevelAlert, format, args)
 }
 
 // Emergency writes an emergency message to the log.
 func Emergency(args ...interface{}) {
-	mux.Lock()
-	defer mux.Unlock()
-	if threshold >= LevelEmergency {
-		_printval("EMERGENCY", args)
-	}
+	_printval(LevelEmergency, args)
 }
 
 // Emergencyf writes an emergency message to the log.
 func Emergencyf(format string, args ...interface{}) {
-	mux.Lock()
-	defer mux.Unlock()
-	if threshold >= LevelEmergency {
-		_printstr("EMERGENCY", fmt.Sprintf(format, args...))
-	}
+	_printstr(LevelEmergency, format, args)
 }
@@ -6,9 +6,11 @@ import (
 	"github.com/marcusva/gadget/log"
 	"github.com/marcusva/gadget/testing/assert"
 	"io/ioutil"
+	stdlog "log"
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestPackage(t *testing.T) {
@@ -43,6 +45,13 @@ func TestLogger(t *testing.T) {
 	log.Init(&buf, log.LevelDebug, true)
 	logger2 := log.Logger()
 	assert.NotEqual(t, logger, logger2)
+
+	// The logger keeps the standard flags
+	logger2.Print("message")
+	assert.Equal(t, logger2.Flags(), stdlog.LstdFlags)
+	_, err := time.ParseInLocation(log.TimeLayout, buf.String()[:len(log.TimeLayout)], time.Local)
+	assert.FailOnErr(t, err)
+	assert.Equal(t, buf.String()[len(log.TimeLayout):], " message\n")
 }
 
 func TestInitFile(t *testing.T) {
@@ -0,0 +1,48 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+)
+
+var counts [LevelDebug + 1]uint64
+
+// count increases the counter for the passed level.
+func count(level Level) {
+	if level >= LevelEmergency && level <= LevelDebug {
+		atomic.AddUint64(&counts[level], 1)
+	}
+}
+
+// Counts returns the amount of records written per level since the program
+// start. The counts only increase, so that they can be used as counters by
+// monitoring systems. The package github.com/marcusva/gadget/log/metrics
+// publishes them via expvar and HTTP.
+func Counts() map[Level]uint64 {
+	result := make(map[Level]uint64, len(counts))
+	for idx := range counts {
+		result[Level(idx)] = atomic.LoadUint64(&counts[idx])
+	}
+	return result
+}
+
+// WriteMetrics writes the amount of records written per level in the
+// Prometheus text exposition format.
+//
+//	# HELP log_records_total Number of log records written per level.
+//	# TYPE log_records_total counter
+//	log_records_total{level="emergency"} 0
+//	...
+func WriteMetrics(w io.Writer) error {
+	var buf strings.Builder
+	buf.WriteString("# HELP log_records_total Number of log records written per level.\n")
+	buf.WriteString("# TYPE log_records_total counter\n")
+	for idx := range counts {
+		fmt.Fprintf(&buf, "log_records_total{level=\"%s\"} %d\n",
+			strings.ToLower(Level(idx).String()), atomic.LoadUint64(&counts[idx]))
+	}
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
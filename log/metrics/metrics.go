@@ -0,0 +1,32 @@
+// Package metrics publishes the record counters of the
+// github.com/marcusva/gadget/log package via expvar and HTTP. It is kept
+// separate, so that the log package does not depend on net/http.
+package metrics
+
+import (
+	"expvar"
+	"github.com/marcusva/gadget/log"
+	"net/http"
+	"sync"
+)
+
+var publish sync.Once
+
+// PublishExpvar publishes the amount of records written per level as
+// "log.records" via the expvar package. It may be called multiple times.
+func PublishExpvar() {
+	publish.Do(func() {
+		expvar.Publish("log.records", expvar.Func(func() interface{} {
+			return log.Counts()
+		}))
+	})
+}
+
+// Handler returns a http.Handler, which writes the amount of records written
+// per level in the Prometheus text exposition format via log.WriteMetrics.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		log.WriteMetrics(w)
+	})
+}
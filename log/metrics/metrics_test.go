@@ -0,0 +1,43 @@
+package metrics_test
+
+import (
+	"bytes"
+	"expvar"
+	"fmt"
+	"github.com/marcusva/gadget/log"
+	"github.com/marcusva/gadget/log/metrics"
+	"github.com/marcusva/gadget/testing/assert"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	metrics.PublishExpvar()
+	metrics.PublishExpvar()
+
+	var buf bytes.Buffer
+	log.Init(&buf, log.LevelError, false)
+	log.Error("error")
+	v := expvar.Get("log.records")
+	assert.FailIf(t, v == nil, "log.records is not published")
+	assert.FailIfNot(t, strings.Contains(v.String(), `"ERROR":`),
+		"invalid expvar value %s", v.String())
+}
+
+func TestHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log.Init(&buf, log.LevelDebug, false)
+	// The counts are global, so compare against the counts before logging.
+	before := log.Counts()
+	log.Notice("notice")
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	assert.Equal(t, rec.Header().Get("Content-Type"), "text/plain; version=0.0.4")
+	notice := fmt.Sprintf(`log_records_total{level="notice"} %d`, before[log.LevelNotice]+1)
+	assert.FailIfNot(t, strings.Contains(body, notice), "notice count missing in %s", body)
+	debug := fmt.Sprintf(`log_records_total{level="debug"} %d`, before[log.LevelDebug])
+	assert.FailIfNot(t, strings.Contains(body, debug), "debug count missing in %s", body)
+}
@@ -0,0 +1,42 @@
+package log_test
+
+import (
+	"bytes"
+	"github.com/marcusva/gadget/log"
+	"github.com/marcusva/gadget/testing/assert"
+	"strings"
+	"testing"
+)
+
+func TestCounts(t *testing.T) {
+	var buf bytes.Buffer
+	log.Init(&buf, log.LevelWarning, false)
+	before := log.Counts()
+
+	log.Error("error")
+	log.Errorf("error %d", 2)
+	log.Warning("warning")
+	log.Debug("debug")
+
+	counts := log.Counts()
+	assert.Equal(t, counts[log.LevelError]-before[log.LevelError], uint64(2))
+	assert.Equal(t, counts[log.LevelWarning]-before[log.LevelWarning], uint64(1))
+	assert.Equal(t, counts[log.LevelDebug]-before[log.LevelDebug], uint64(0))
+}
+
+func TestWriteMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	log.Init(&buf, log.LevelDebug, false)
+	log.Emergency("emergency")
+
+	var out strings.Builder
+	assert.FailOnErr(t, log.WriteMetrics(&out))
+	body := out.String()
+	assert.FailIfNot(t, strings.Contains(body, "# TYPE log_records_total counter\n"),
+		"type missing in %s", body)
+	assert.FailIfNot(t, strings.Contains(body, `log_records_total{level="emergency"} `),
+		"emergency count missing in %s", body)
+	assert.FailIf(t, strings.Contains(body, `log_records_total{level="emergency"} 0`),
+		"emergency count not increased in %s", body)
+	assert.Equal(t, strings.Count(body, "\nlog_records_total{"), 8)
+}
@@ -0,0 +1,150 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeLayout is the default layout of the timestamp written in front of each
+// log line.
+const TimeLayout = "2006/01/02 15:04:05"
+
+var levelNames = []string{
+	"EMERGENCY", "ALERT", "CRITICAL", "ERROR", "WARNING", "NOTICE", "INFO",
+	"DEBUG",
+}
+
+// String returns the name of the Level as used in the log output.
+func (level Level) String() string {
+	if level < LevelEmergency || level > LevelDebug {
+		return fmt.Sprintf("Level(%d)", level)
+	}
+	return levelNames[level]
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (level Level) MarshalText() ([]byte, error) {
+	if level < LevelEmergency || level > LevelDebug {
+		return nil, fmt.Errorf("invalid log level %d", level)
+	}
+	return []byte(levelNames[level]), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. It accepts
+// the names written to the log output as well as all values understood by
+// GetLogLevel.
+func (level *Level) UnmarshalText(text []byte) error {
+	lv, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*level = lv
+	return nil
+}
+
+// ParseLevel gets a matching Level value from the passed string. In contrast
+// to GetLogLevel, it also accepts the upper-case level names used in the log
+// output.
+func ParseLevel(level string) (Level, error) {
+	for idx, name := range levelNames {
+		if level == name {
+			return Level(idx), nil
+		}
+	}
+	return GetLogLevel(level)
+}
+
+// Record represents a single log entry.
+type Record struct {
+	// Time is the point in time, the entry was written.
+	Time time.Time `json:"time"`
+	// Level is the severity of the entry.
+	Level Level `json:"level"`
+	// File is the base name of the source file, the entry was written from.
+	// It is empty, if the caller information was not logged.
+	File string `json:"file,omitempty"`
+	// Line is the line within File, the entry was written from.
+	Line int `json:"line,omitempty"`
+	// Message is the logged message.
+	Message string `json:"message"`
+	// Fields contains additional key-value pairs passed to the logging
+	// functions.
+	Fields []Field `json:"fields,omitempty"`
+}
+
+// Field is an additional key-value pair of a Record. Fields can be passed as
+// arguments to any of the logging functions and will be written after the
+// message.
+//
+//	log.Info("user logged in", log.F("user", name), log.F("remote", addr))
+type Field struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// F creates a new Field.
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// String returns the Field as key=value pair. Values containing whitespace or
+// quotes are quoted.
+func (f Field) String() string {
+	value := fmt.Sprint(f.Value)
+	if value == "" || strings.ContainsAny(value, " \t\r\n\"=") {
+		value = strconv.Quote(value)
+	}
+	return f.Key + "=" + value
+}
+
+// ParseRecord parses a single line, as written by the logging functions of
+// the package with the default TextFormatter, into a Record. The time is
+// interpreted as local time.
+func ParseRecord(line string) (*Record, error) {
+	line = strings.TrimRight(line, "\r\n")
+	if len(line) < len(TimeLayout)+2 {
+		return nil, errors.New("line too short for a log record")
+	}
+	tm, err := time.ParseInLocation(TimeLayout, line[:len(TimeLayout)], time.Local)
+	if err != nil {
+		return nil, err
+	}
+	rest := line[len(TimeLayout)+1:]
+	end := strings.IndexByte(rest, ' ')
+	if end == -1 {
+		end = len(rest)
+	}
+	level, err := ParseLevel(rest[:end])
+	if err != nil {
+		return nil, err
+	}
+	rec := &Record{Time: tm, Level: level}
+	rest = strings.TrimLeft(rest[end:], " ")
+
+	// The optional caller information is the first bracketed token of the
+	// form [file:line], which is followed by a space or the end of the line.
+	// Messages of the non-formatting logging functions start with "[[".
+	if len(rest) > 1 && rest[0] == '[' && rest[1] != '[' {
+		if end = strings.IndexByte(rest, ']'); end > 0 && (end == len(rest)-1 || rest[end+1] == ' ') {
+			caller := rest[1:end]
+			if sep := strings.LastIndexByte(caller, ':'); sep > 0 {
+				if lineno, err := strconv.Atoi(caller[sep+1:]); err == nil {
+					rec.File = caller[:sep]
+					rec.Line = lineno
+					rest = strings.TrimPrefix(rest[end+1:], " ")
+				}
+			}
+		}
+	}
+	rec.Message = rest
+	return rec, nil
+}
+
+// String returns the Record in the format written by the logging functions
+// of the package.
+func (rec *Record) String() string {
+	return string(TextFormatter{}.format(rec, nil))
+}
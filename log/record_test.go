@@ -0,0 +1,94 @@
+package log_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/marcusva/gadget/log"
+	"github.com/marcusva/gadget/testing/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLevelString(t *testing.T) {
+	assert.Equal(t, log.LevelEmergency.String(), "EMERGENCY")
+	assert.Equal(t, log.LevelWarning.String(), "WARNING")
+	assert.Equal(t, log.LevelDebug.String(), "DEBUG")
+	assert.Equal(t, log.Level(12).String(), "Level(12)")
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, v := range []string{"WARNING", "Warning", "4"} {
+		level, err := log.ParseLevel(v)
+		assert.FailOnErr(t, err)
+		assert.Equal(t, level, log.LevelWarning)
+	}
+	_, err := log.ParseLevel("warn")
+	assert.Err(t, err)
+}
+
+func TestParseRecord(t *testing.T) {
+	var buf bytes.Buffer
+	log.Init(&buf, log.LevelDebug, true)
+	log.Warningf("disk %s is almost full", "sda1")
+	log.Init(&buf, log.LevelDebug, false)
+	log.Emergency("test")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.FailIfNot(t, len(lines) == 2, "unexpected output: %v", lines)
+
+	rec, err := log.ParseRecord(lines[0])
+	assert.FailOnErr(t, err)
+	assert.Equal(t, rec.Level, log.LevelWarning)
+	assert.Equal(t, rec.File, "record_test.go")
+	assert.FailIf(t, rec.Line == 0, "line is not set")
+	assert.Equal(t, rec.Message, "disk sda1 is almost full")
+	assert.FailIf(t, time.Since(rec.Time) > time.Minute, "invalid time")
+	assert.Equal(t, rec.String(), lines[0])
+
+	rec, err = log.ParseRecord(lines[1])
+	assert.FailOnErr(t, err)
+	assert.Equal(t, rec.Level, log.LevelEmergency)
+	assert.Equal(t, rec.File, "")
+	assert.Equal(t, rec.Message, "[[test]]")
+	assert.Equal(t, rec.String(), lines[1])
+
+	// Messages, which look like caller information
+	for _, msg := range []string{"[[host:80]]", "[host:80]x", "[[a:1] b]"} {
+		rec, err = log.ParseRecord("2018/01/02 15:04:05 INFO      " + msg)
+		assert.FailOnErr(t, err)
+		assert.Equal(t, rec.File, "", msg)
+		assert.Equal(t, rec.Line, 0, msg)
+		assert.Equal(t, rec.Message, msg)
+	}
+	rec, err = log.ParseRecord("2018/01/02 15:04:05 INFO      [main.go:12] [[host:80]]")
+	assert.FailOnErr(t, err)
+	assert.Equal(t, rec.File, "main.go")
+	assert.Equal(t, rec.Line, 12)
+	assert.Equal(t, rec.Message, "[[host:80]]")
+
+	invalid := []string{
+		"",
+		"2018/01/02 15:04:05",
+		"2018-01-02 15:04:05 DEBUG     test",
+		"2018/01/02 15:04:05 VERBOSE   test",
+	}
+	for _, line := range invalid {
+		_, err = log.ParseRecord(line)
+		assert.Err(t, err, "invalid line '%s' was accepted", line)
+	}
+}
+
+func TestRecordJSON(t *testing.T) {
+	rec, err := log.ParseRecord("2018/01/02 15:04:05 NOTICE    [main.go:12] started")
+	assert.FailOnErr(t, err)
+
+	data, err := json.Marshal(rec)
+	assert.FailOnErr(t, err)
+	assert.FailIfNot(t, bytes.Contains(data, []byte(`"level":"NOTICE"`)),
+		"level not found in %s", data)
+
+	rec2 := &log.Record{}
+	assert.FailOnErr(t, json.Unmarshal(data, rec2))
+	assert.Equal(t, rec2.String(), rec.String())
+}
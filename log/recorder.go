@@ -0,0 +1,93 @@
+package log
+
+// flightRecorder is a ring buffer, which keeps the last records of all
+// levels.
+type flightRecorder struct {
+	records []*Record
+	written []bool
+	next    int
+	trigger Level
+}
+
+var recorder *flightRecorder
+
+// add adds a record to the buffer. written indicates, if the record has been
+// written to the log already.
+func (fr *flightRecorder) add(rec *Record, written bool) {
+	fr.records[fr.next] = rec
+	fr.written[fr.next] = written
+	fr.next = (fr.next + 1) % len(fr.records)
+}
+
+// dump passes all buffered records, which were not written before, in
+// chronological order to fn and clears the buffer.
+func (fr *flightRecorder) dump(fn func(*Record)) {
+	size := len(fr.records)
+	for idx := 0; idx < size; idx++ {
+		pos := (fr.next + idx) % size
+		if fr.records[pos] != nil && !fr.written[pos] {
+			fn(fr.records[pos])
+		}
+		fr.records[pos] = nil
+	}
+	fr.next = 0
+}
+
+// EnableFlightRecorder keeps the last size records of all levels in memory,
+// regardless of the current threshold. Whenever a record with the trigger
+// level or a more severe one is written, the buffered records, which were
+// discarded due to the threshold, are written in front of it. This allows to
+// get the debug context of severe problems without writing all debug
+// records:
+//
+//	log.Init(out, log.LevelWarning, true)
+//	log.EnableFlightRecorder(1000, log.LevelCritical)
+//
+// Since a record has to be created for each call to a logging function,
+// Lazy arguments are evaluated for all records while the flight recorder is
+// enabled. If size is smaller than 1, the flight recorder is disabled.
+// Calling Init disables the flight recorder.
+func EnableFlightRecorder(size int, trigger Level) {
+	mux.Lock()
+	defer mux.Unlock()
+	if size < 1 {
+		recorder = nil
+		return
+	}
+	recorder = &flightRecorder{
+		records: make([]*Record, size),
+		written: make([]bool, size),
+		trigger: trigger,
+	}
+}
+
+// DisableFlightRecorder disables the flight recorder and discards all
+// buffered records.
+func DisableFlightRecorder() {
+	EnableFlightRecorder(0, LevelEmergency)
+}
+
+// DumpFlightRecorder writes all buffered records of the flight recorder,
+// which were discarded due to the threshold, to the log.
+func DumpFlightRecorder() {
+	defer reportErrors()
+	mux.Lock()
+	defer mux.Unlock()
+	if recorder != nil {
+		recorder.dump(write)
+	}
+}
+
+// DumpOnPanic writes all buffered records of the flight recorder to the log,
+// if the program panics. It has to be deferred and passes the panic on.
+//
+//	func main() {
+//	    defer log.DumpOnPanic()
+//	    ...
+//	}
+func DumpOnPanic() {
+	if r := recover(); r != nil {
+		DumpFlightRecorder()
+		panic(r)
+	}
+}
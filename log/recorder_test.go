@@ -0,0 +1,85 @@
+package log_test
+
+import (
+	"bytes"
+	"github.com/marcusva/gadget/log"
+	"github.com/marcusva/gadget/testing/assert"
+	"strings"
+	"testing"
+)
+
+func messages(buf *bytes.Buffer) []string {
+	var result []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		rec, err := log.ParseRecord(line)
+		if err != nil {
+			panic(err)
+		}
+		result = append(result, rec.Level.String()+" "+rec.Message)
+	}
+	buf.Reset()
+	return result
+}
+
+func TestFlightRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	log.Init(&buf, log.LevelWarning, false)
+	log.EnableFlightRecorder(3, log.LevelCritical)
+	defer log.DisableFlightRecorder()
+
+	log.Debugf("debug %d", 1)
+	log.Debugf("debug %d", 2)
+	log.Infof("info %d", 3)
+	log.Errorf("error %d", 4)
+	assert.Equal(t, messages(&buf), []string{"ERROR error 4"})
+
+	// Only the last 3 records are kept and records written before are not
+	// written again.
+	log.Criticalf("critical %d", 5)
+	assert.Equal(t, messages(&buf), []string{
+		"DEBUG debug 2", "INFO info 3", "CRITICAL critical 5",
+	})
+
+	// The buffer is cleared after dumping it
+	log.Alertf("alert %d", 6)
+	assert.Equal(t, messages(&buf), []string{"ALERT alert 6"})
+
+	log.Noticef("notice %d", 7)
+	log.DumpFlightRecorder()
+	assert.Equal(t, messages(&buf), []string{"NOTICE notice 7"})
+	log.DumpFlightRecorder()
+	assert.Equal(t, len(messages(&buf)), 0)
+
+	// Buffered records are not counted
+	before := log.Counts()[log.LevelDebug]
+	log.Debug("debug")
+	assert.Equal(t, log.Counts()[log.LevelDebug], before)
+
+	log.DisableFlightRecorder()
+	log.Debug("debug")
+	log.Critical("critical")
+	assert.Equal(t, messages(&buf), []string{"CRITICAL [[critical]]"})
+}
+
+func TestDumpOnPanic(t *testing.T) {
+	var buf bytes.Buffer
+	log.Init(&buf, log.LevelError, false)
+	log.EnableFlightRecorder(10, log.LevelEmergency)
+	defer log.DisableFlightRecorder()
+
+	assert.Panics(t, func() {
+		defer log.DumpOnPanic()
+		log.Info("before panic")
+		panic("test")
+	})
+	assert.Equal(t, messages(&buf), []string{"INFO [[before panic]]"})
+
+	// Init disables the flight recorder
+	log.Init(&buf, log.LevelError, false)
+	log.Info("info")
+	log.DumpFlightRecorder()
+	assert.Equal(t, buf.Len(), 0)
+}
@@ -0,0 +1,91 @@
+package log
+
+import (
+	"bytes"
+	"log"
+	"sync"
+)
+
+// Writer is an io.Writer, which writes each line passed to it as record with
+// a fixed level to the log. It can be used to capture the output of other
+// packages, such as the standard error of an exec.Cmd or the ErrorLog of a
+// http.Server:
+//
+//	server := &http.Server{
+//	    ErrorLog: stdlog.New(log.NewWriter(log.LevelError, "http"), "", 0),
+//	}
+//
+// Incomplete lines are buffered until the line is completed or the Writer is
+// closed.
+type Writer struct {
+	level     Level
+	component string
+	buf       []byte
+	mux       sync.Mutex
+}
+
+// NewWriter creates a new Writer, which writes each line with the passed
+// level. If component is not empty, it is added as "component" field to each
+// record.
+func NewWriter(level Level, component string) *Writer {
+	return &Writer{level: level, component: component}
+}
+
+// Write writes all complete lines of p to the log.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx == -1 {
+			break
+		}
+		w.writeLine(w.buf[:idx])
+		w.buf = w.buf[idx+1:]
+	}
+	if len(w.buf) == 0 {
+		w.buf = nil
+	}
+	return len(p), nil
+}
+
+// Close writes any buffered, incomplete line to the log.
+func (w *Writer) Close() error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	w.writeLine(w.buf)
+	w.buf = nil
+	return nil
+}
+
+func (w *Writer) writeLine(line []byte) {
+	line = bytes.TrimRight(line, "\r")
+	if len(line) == 0 {
+		return
+	}
+	var fields []Field
+	if w.component != "" {
+		fields = []Field{F("component", w.component)}
+	}
+	_printmsg(w.level, -1, string(line), fields)
+}
+
+// CaptureStdLog redirects the output of the standard library's default
+// logger to a Writer with the passed level and component. The returned
+// function restores the previous output, flags and prefix of the standard
+// library's logger. CaptureStdLog requires Go 1.13 or newer.
+func CaptureStdLog(level Level, component string) (restore func()) {
+	out, flags, prefix := log.Writer(), log.Flags(), log.Prefix()
+	w := NewWriter(level, component)
+	log.SetOutput(w)
+	// The time and caller information are provided by this package.
+	log.SetFlags(0)
+	log.SetPrefix("")
+	return func() {
+		log.SetOutput(out)
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+		w.Close()
+	}
+}
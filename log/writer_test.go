@@ -0,0 +1,69 @@
+package log_test
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/marcusva/gadget/log"
+	"github.com/marcusva/gadget/testing/assert"
+	stdlog "log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	log.Init(&buf, log.LevelDebug, true)
+
+	w := log.NewWriter(log.LevelWarning, "worker")
+	fmt.Fprint(w, "first line\r\nsecond")
+	fmt.Fprint(w, " line\n\nincomplete")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.FailIfNot(t, len(lines) == 2, "unexpected output: %v", lines)
+	for _, line := range lines {
+		rec, err := log.ParseRecord(line)
+		assert.FailOnErr(t, err)
+		assert.Equal(t, rec.Level, log.LevelWarning)
+		assert.Equal(t, rec.File, "")
+	}
+	assert.FailIfNot(t, strings.HasSuffix(lines[0], " first line component=worker"),
+		"unexpected output: %s", lines[0])
+	assert.FailIfNot(t, strings.HasSuffix(lines[1], " second line component=worker"),
+		"unexpected output: %s", lines[1])
+
+	buf.Reset()
+	assert.NoErr(t, w.Close())
+	assert.FailIfNot(t, strings.HasSuffix(buf.String(), " incomplete component=worker\n"),
+		"unexpected output: %s", buf.String())
+
+	// The threshold applies to the lines
+	buf.Reset()
+	log.Init(&buf, log.LevelError, false)
+	fmt.Fprintln(log.NewWriter(log.LevelInfo, ""), "info")
+	assert.Equal(t, buf.Len(), 0)
+}
+
+func TestCaptureStdLog(t *testing.T) {
+	var buf bytes.Buffer
+	log.Init(&buf, log.LevelDebug, false)
+
+	var stdout bytes.Buffer
+	stdlog.SetOutput(&stdout)
+	stdlog.SetPrefix("std: ")
+	restore := log.CaptureStdLog(log.LevelNotice, "stdlib")
+	stdlog.Printf("captured %d", 1)
+	restore()
+	stdlog.Print("not captured")
+
+	result := strings.TrimSpace(buf.String())
+	assert.FailIfNot(t, strings.HasSuffix(result, "NOTICE    captured 1 component=stdlib"),
+		"unexpected output: %s", result)
+	assert.FailIfNot(t, strings.HasPrefix(stdout.String(), "std: "),
+		"unexpected output: %s", stdout.String())
+	assert.FailIfNot(t, strings.HasSuffix(stdout.String(), " not captured\n"),
+		"unexpected output: %s", stdout.String())
+	assert.Equal(t, stdlog.Flags(), stdlog.LstdFlags)
+	stdlog.SetOutput(os.Stderr)
+	stdlog.SetPrefix("")
+}